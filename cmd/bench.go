@@ -0,0 +1,73 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/handlers"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	commandLineInput := common.BenchCmdArgsAndFlags{}
+
+	// benchCmd represents the bench command
+	benchCmd := &cobra.Command{
+		Use:        "bench",
+		SuggestFor: []string{"bnch", "benchmark", "perf"},
+		Short:      "bench measures the transfer performance against a container.",
+		Long: `bench generates synthetic files and uploads them to the given container through the transfer engine, optionally downloading them back.
+It reports the throughput, the latency percentiles of the chunks and the failures of each run. The blobs uploaded are deleted afterwards.
+Block sizes and worker counts can be swept to find the best fitting configuration.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			// the only argument to this command should be the url of the container
+			if len(args) != 1 {
+				return errors.New("this command requires the url of the container to benchmark against")
+			}
+			if determineLocaltionType(args[0]) != common.Blob {
+				return errors.New("the provided container url is invalid")
+			}
+			if commandLineInput.FileCount == 0 || commandLineInput.FileSize <= 0 {
+				return errors.New("the number and the size of the files to generate should be positive")
+			}
+			commandLineInput.ContainerUrl = args[0]
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			handlers.HandleBenchCommand(commandLineInput)
+		},
+	}
+
+	rootCmd.AddCommand(benchCmd)
+
+	// define the flags relevant to the bench command
+
+	// options
+	benchCmd.PersistentFlags().Uint32Var(&commandLineInput.FileCount, "file-count", 10, "Number of files to generate.")
+	benchCmd.PersistentFlags().Int64Var(&commandLineInput.FileSize, "file-size", 64*1024*1024, "Size in bytes of each file to generate.")
+	benchCmd.PersistentFlags().BoolVar(&commandLineInput.InMemory, "in-memory", false, "Generate the files in memory (/dev/shm, only on Linux) instead of a temp dir, so that the disk does not limit the results.")
+	benchCmd.PersistentFlags().BoolVar(&commandLineInput.Download, "download", false, "Download the files back after uploading them.")
+	benchCmd.PersistentFlags().Uint32Var(&commandLineInput.BlockSize, "block-size", common.DefaultBlockSize, "Use this block size when transferring the files.")
+	benchCmd.PersistentFlags().StringVar(&commandLineInput.SweepBlockSizes, "sweep-block-sizes", "", "Comma separated list of block sizes in bytes to run the benchmark with, overriding --block-size.")
+	benchCmd.PersistentFlags().StringVar(&commandLineInput.SweepWorkers, "sweep-workers", "", "Comma separated list of worker counts of the transfer engine to run the benchmark with.")
+	benchCmd.PersistentFlags().Uint8Var(&commandLineInput.LogVerbosity, "Logging level", uint8(common.LOG_DEBUG_LEVEL), "defines the log verbosity to be saved to log file")
+}
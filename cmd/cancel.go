@@ -0,0 +1,54 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/handlers"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	commandLineInput := common.CancelCmdArgsAndFlags{}
+
+	// cancelCmd represents the cancel command
+	cancelCmd := &cobra.Command{
+		Use:        "cancel",
+		SuggestFor: []string{"cancl", "cancle", "abort"},
+		Short:      "cancel cancels an existing job.",
+		Long: `cancel cancels all the transfers of an existing job which are not complete yet.
+The cancelled transfers are recorded in the job's plan files, so the job can be listed or resumed afterwards.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			// the only argument to this command should be the id of the job to cancel
+			if len(args) != 1 {
+				return errors.New("this command requires the id of the job to cancel")
+			}
+			commandLineInput.JobId = args[0]
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			handlers.HandleCancelCommand(commandLineInput)
+		},
+	}
+
+	rootCmd.AddCommand(cancelCmd)
+}
@@ -22,11 +22,11 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"github.com/spf13/cobra"
 	"errors"
 	"github.com/Azure/azure-storage-azcopy/handlers"
 	"github.com/Azure/azure-storage-azcopy/common"
-	"net/http"
 )
 
 // TODO check file size, max is 4.75TB
@@ -40,12 +40,33 @@ func init() {
 		SuggestFor: []string{"cpy", "cy", "mv"}, //TODO why does message appear twice on the console
 		Short: "copy(cp) moves data between two places.",
 		Long: `copy(cp) moves data between two places. The most common cases are:
-  - Upload local files/directories into Azure Storage.
-  - Download blobs/container from Azure Storage to local file system.
-  - Coming soon: Transfer files from Amazon S3 to Azure Storage.
+  - Upload local files/directories into a container or virtual directory in Azure Storage, sub-directories included with --recursive.
+  - Download blobs/container/virtual directory from Azure Storage to local file system, e.g. https://account.blob.core.windows.net/container/dir/.
+    The virtual directories of the blobs become local directories.
+    The snapshots of the blobs are downloaded as well with --with-snapshots, each next to its blob with its time appended.
+  - Upload or download only the files named in --list-of-files, given relative to the source directory, container or virtual directory.
+    The source is not enumerated, which saves listing a large tree or container to transfer a few of its files.
+  - Copy blobs/container between containers or accounts in Azure Storage, server side, virtual sub-directories included with --recursive.
+    The service performs the copy (Start Copy), its progress is tracked in chunks of the block size.
+  - Upload local files/directories into an Azure Files share, and download files/directories from it.
+    Shares at a custom endpoint, such as a local emulator, are recognized when it is set in AZS_FILE_ENDPOINT.
+  - Transfer objects/buckets from Amazon S3, or an S3-compatible object storage, to Azure Storage.
+    The credentials are read from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN, and handed to the transfer engine with the job.
+    The region is taken from the url, e.g. https://bucket.s3.us-west-2.amazonaws.com, or else from the responses of S3.
+    Buckets at a custom endpoint are recognized when it is set in AZS_S3_ENDPOINT.
   - Coming soon: Transfer files from Azure Storage to Amazon S3.
-  - Coming soon: Transfer files from Google Storage to Azure Storage.
-  - Coming soon: Transfer files from Azure Storage to Google Storage.`,
+  - Transfer objects/buckets from Google Cloud Storage to Azure Storage, through its XML API.
+    The HMAC key is read from GCS_ACCESS_KEY_ID and GCS_SECRET_ACCESS_KEY.
+    Buckets at a custom endpoint are recognized when it is set in AZS_GCS_ENDPOINT.
+  - Coming soon: Transfer files from Azure Storage to Google Storage.
+  - Upload a file served at any other http(s) url into Azure Storage as a block blob, given --from-http.
+    Ranges of the file are fetched in parallel if the server supports them, otherwise the file is read as a single stream.
+  - Upload the standard input into a block blob, given the source -, e.g. pg_dump db | azs copy - <blob url>.
+    Download a blob to the standard output, given the destination -, e.g. azs copy <blob url> - | gunzip.
+    Streams are transferred by azs itself rather than by the storage engine, so they cannot be paused or resumed.
+  - Upload a local directory as a single tar blob with --archive tar, compressed with --gzip, and extract a tar blob
+    into a local directory with --extract. Archives are streams as well, the archive is never written to disk.
+  Urls which are not recognized as Azure Files, S3 or GCS are taken as Blob storage.`,
 		Args: func(cmd *cobra.Command, args []string) error {
 			// the only arguments to this command should be a source and destination
 			if len(args) < 2 {
@@ -62,10 +83,84 @@ func init() {
 				return errors.New("the provided destination is invalid")
 			}
 
-			if sourceType == common.Blob && destinationType == common.Blob || sourceType == common.Local && destinationType == common.Local {
+			if sourceType == common.Local && destinationType == common.Local {
 				return errors.New("the provided source/destination pair is invalid")
 			}
 
+			// Azure Files shares can only be uploaded to and downloaded from
+			if (sourceType == common.File || destinationType == common.File) && sourceType != common.Local && destinationType != common.Local {
+				return errors.New("the provided source/destination pair is invalid")
+			}
+			// S3 and GCS buckets can only be copied from, into Azure Storage
+			if destinationType == common.S3 || destinationType == common.GCS ||
+				(sourceType == common.S3 || sourceType == common.GCS) && destinationType != common.Blob {
+				return errors.New("the provided source/destination pair is invalid")
+			}
+			// the standard input can only be uploaded into a block blob, and only a blob can be downloaded to the standard output
+			if sourceType == common.Pipe && destinationType != common.Blob || destinationType == common.Pipe && sourceType != common.Blob {
+				return errors.New("the provided source/destination pair is invalid")
+			}
+			// a plain http(s) url can only be uploaded from, into a block blob
+			if commandLineInput.FromHttp {
+				if sourceType == common.Local || sourceType == common.Pipe || destinationType != common.Blob {
+					return errors.New("only a http(s) url can be read with from-http, into a block blob")
+				}
+				sourceType = common.Http
+			}
+			if destinationType == common.File && commandLineInput.BlockSize > common.MaxFileRangeSize {
+				return fmt.Errorf("the block size of files should be at most %d bytes", common.MaxFileRangeSize)
+			}
+
+			// pages of page blobs are 512 bytes, and at most 4MB of pages or of an append block can be written at once
+			blobType, err := common.ParseBlobType(commandLineInput.BlobType)
+			if err != nil {
+				return err
+			}
+			if blobType == common.PageBlob && commandLineInput.BlockSize != 0 &&
+				(commandLineInput.BlockSize%common.PageSize != 0 || commandLineInput.BlockSize > common.MaxPageBlobChunkSize) {
+				return fmt.Errorf("the block size of page blobs should be a multiple of %d bytes and at most %d bytes", common.PageSize, common.MaxPageBlobChunkSize)
+			}
+			if blobType == common.AppendBlob && commandLineInput.BlockSize > common.MaxAppendBlockSize {
+				return fmt.Errorf("the block size of append blobs should be at most %d bytes", common.MaxAppendBlockSize)
+			}
+			if sourceType == common.Pipe && blobType != common.BlockBlob {
+				return errors.New("the standard input can only be uploaded as a block blob")
+			}
+
+			// a local tree can only be archived into a block blob, and an archive blob only extracted into a local directory
+			if commandLineInput.Archive != "" && commandLineInput.Archive != handlers.ArchiveTar {
+				return fmt.Errorf("invalid archive format %s. The valid archive format is %s", commandLineInput.Archive, handlers.ArchiveTar)
+			}
+			if commandLineInput.Archive != "" && (sourceType != common.Local || destinationType != common.Blob || blobType != common.BlockBlob) {
+				return errors.New("only local files and directories can be archived, into a block blob")
+			}
+			if commandLineInput.Gzip && commandLineInput.Archive == "" {
+				return errors.New("gzip can only be used along with archive")
+			}
+			if commandLineInput.Extract && (sourceType != common.Blob || destinationType != common.Local) {
+				return errors.New("only a blob can be extracted, into a local directory")
+			}
+			if commandLineInput.WithSnapshots && (sourceType != common.Blob || destinationType != common.Local || commandLineInput.Extract) {
+				return errors.New("snapshots can only be included when downloading blobs into a local directory")
+			}
+			if commandLineInput.ListOfFiles != "" {
+				if !(sourceType == common.Local && destinationType == common.Blob || sourceType == common.Blob && destinationType == common.Local) ||
+					commandLineInput.Archive != "" || commandLineInput.Extract || commandLineInput.WithSnapshots {
+					return errors.New("a list of files can only be given when uploading local files into blobs or downloading blobs into local files")
+				}
+				if listInfo, err := os.Stat(commandLineInput.ListOfFiles); err != nil || listInfo.IsDir() {
+					return fmt.Errorf("the list of files %s cannot be read", commandLineInput.ListOfFiles)
+				}
+			}
+
+			// the filters are semicolon separated glob patterns
+			if err := handlers.ValidateNamePatterns(commandLineInput.Include); err != nil {
+				return err
+			}
+			if err := handlers.ValidateNamePatterns(commandLineInput.Exclude); err != nil {
+				return err
+			}
+
 			commandLineInput.Source = args[0]
 			commandLineInput.Destination = args[1]
 			commandLineInput.SourceType = sourceType
@@ -86,15 +181,17 @@ func init() {
 	// define the flags relevant to the cp command
 
 	// filters
-	cpCmd.PersistentFlags().StringVar(&commandLineInput.Include, "include", "", "Filter: Include these files when copying. Support use of *.")
-	cpCmd.PersistentFlags().StringVar(&commandLineInput.Exclude, "exclude", "", "Filter: Exclude these files when copying. Support use of *.")
-	cpCmd.PersistentFlags().BoolVar(&commandLineInput.Recursive, "recursive", false, "Filter: Look into sub-directories recursively when uploading from local file system.")
-	cpCmd.PersistentFlags().BoolVar(&commandLineInput.FollowSymlinks, "follow-symlinks", false, "Filter: Follow symbolic links when uploading from local file system.")
-	cpCmd.PersistentFlags().BoolVar(&commandLineInput.WithSnapshots, "with-snapshots", false, "Filter: Include the snapshots. Only valid when the source is blobs.")
+	cpCmd.PersistentFlags().StringVar(&commandLineInput.Include, "include", "", "Filter: Include only the files matching these semicolon separated patterns when copying, e.g. \"*.csv;logs/**/*.txt\". Patterns with a / are matched against the relative path, ** matching any number of directories.")
+	cpCmd.PersistentFlags().StringVar(&commandLineInput.Exclude, "exclude", "", "Filter: Exclude the files matching these semicolon separated patterns when copying, e.g. \"_tmp/**\". Patterns are matched as with --include.")
+	cpCmd.PersistentFlags().BoolVar(&commandLineInput.Recursive, "recursive", false, "Filter: Look into sub-directories recursively when uploading from local file system, or into virtual sub-directories when copying a container server side.")
+	cpCmd.PersistentFlags().BoolVar(&commandLineInput.FollowSymlinks, "follow-symlinks", false, "Filter: Follow symbolic links when uploading from local file system, the files keep the paths of the links. Links are skipped otherwise.")
+	cpCmd.PersistentFlags().StringVar(&commandLineInput.ListOfFiles, "list-of-files", "", "Filter: Transfer only the files named in this file, one relative path or blob name per line, instead of enumerating the source directory or container.")
+	cpCmd.PersistentFlags().BoolVar(&commandLineInput.WithSnapshots, "with-snapshots", false, "Filter: Include the snapshots when downloading a container or virtual directory, each snapshot is named after its blob followed by its time, e.g. name.2018-01-02T03-04-05.0000000Z.")
 
 	// options
+	cpCmd.PersistentFlags().BoolVar(&commandLineInput.FromHttp, "from-http", false, "Read the source url as a file served over plain http(s), which supports range requests or is read as a single stream.")
 	cpCmd.PersistentFlags().Uint32Var(&commandLineInput.BlockSize, "block-size", 0, "Use this block size when uploading to Azure Storage.")
-	cpCmd.PersistentFlags().StringVar(&commandLineInput.BlobType, "blob-type", "block", "Upload to Azure Storage using this blob type.")
+	cpCmd.PersistentFlags().StringVar(&commandLineInput.BlobType, "blob-type", "block", "Upload to Azure Storage using this blob type: block, page or append. The size of files uploaded as page blobs should be a multiple of 512 bytes.")
 	cpCmd.PersistentFlags().StringVar(&commandLineInput.BlobTier, "blob-tier", "", "Upload to Azure Storage using this blob tier.")
 	cpCmd.PersistentFlags().StringVar(&commandLineInput.Metadata, "metadata", "", "Upload to Azure Storage with these key-value pairs as metadata.")
 	cpCmd.PersistentFlags().StringVar(&commandLineInput.ContentType, "content-type", "", "Specifies content type of the file. Implies no-guess-mime-type.")
@@ -102,6 +199,9 @@ func init() {
 	cpCmd.PersistentFlags().BoolVar(&commandLineInput.NoGuessMimeType, "no-guess-mime-type", false, "This sets the content-type based on the extension of the file.")
 	cpCmd.PersistentFlags().BoolVar(&commandLineInput.PreserveLastModifiedTime, "preserve-last-modified-time", false, "Only available when destination is file system.")
 	cpCmd.PersistentFlags().BoolVar(&commandLineInput.IsaBackgroundOp, "background-op", false, "true if user has to perform the operations as a background operation")
+	cpCmd.PersistentFlags().StringVar(&commandLineInput.Archive, "archive", "", "Upload the local files/directories as a single archive blob of this format: tar.")
+	cpCmd.PersistentFlags().BoolVar(&commandLineInput.Gzip, "gzip", false, "Compress the archive with gzip. Only valid along with archive.")
+	cpCmd.PersistentFlags().BoolVar(&commandLineInput.Extract, "extract", false, "Extract the tar blob, compressed with gzip or not, into the destination directory.")
 	cpCmd.PersistentFlags().StringVar(&commandLineInput.Acl, "acl", "", "Access conditions to be used when uploading/downloading from Azure Storage.")
 	cpCmd.PersistentFlags().Uint8Var(&commandLineInput.LogVerbosity, "Logging level", uint8(common.LOG_DEBUG_LEVEL), "defines the log verbosity to be saved to log file")
 }
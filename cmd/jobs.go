@@ -0,0 +1,79 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/handlers"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+func init() {
+	cleanCommandLineInput := common.JobsCleanCmdArgsAndFlags{}
+	olderThan := time.Duration(0)
+
+	// jobsCmd represents the jobs command
+	jobsCmd := &cobra.Command{
+		Use:        "jobs",
+		SuggestFor: []string{"job", "jbos"},
+		Short:      "jobs manages the existing jobs of the transfer engine.",
+		Long:       `jobs manages the existing jobs of the transfer engine.`,
+	}
+
+	// jobsCleanCmd represents the jobs clean command
+	jobsCleanCmd := &cobra.Command{
+		Use:        "clean",
+		SuggestFor: []string{"clen", "claen", "purge"},
+		Short:      "clean removes completed jobs.",
+		Long: `clean removes completed jobs from the transfer engine, along with their plan files and log files.
+By default the completed jobs whose time to live has passed are removed. The transfer engine can also sweep the jobs whose time to live has passed by itself, every AZS_JOB_SWEEP_INTERVAL (e.g. 1h) if the variable is set in its environment.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return errors.New("this command does not take any argument, use the --job flag to clean a single job")
+			}
+			if olderThan < 0 {
+				return errors.New("the value of --older-than cannot be negative")
+			}
+			// the transfer engine is given the duration in seconds
+			if olderThan%time.Second != 0 {
+				return errors.New("the value of --older-than must be a whole number of seconds")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if cmd.Flags().Changed("older-than") {
+				cleanCommandLineInput.OlderThan = &olderThan
+			}
+			handlers.HandleJobsCleanCommand(cleanCommandLineInput)
+		},
+	}
+
+	rootCmd.AddCommand(jobsCmd)
+	jobsCmd.AddCommand(jobsCleanCmd)
+
+	// define the flags relevant to the jobs clean command
+
+	// filters
+	jobsCleanCmd.PersistentFlags().DurationVar(&olderThan, "older-than", 0, "Filter: remove the jobs completed more than this long ago, e.g. 72h, instead of the ones whose time to live has passed")
+	jobsCleanCmd.PersistentFlags().StringVar(&cleanCommandLineInput.JobId, "job", "", "Filter: only remove the job with this id")
+}
@@ -0,0 +1,77 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/handlers"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	commandLineInput := common.ListRemoteCmdArgsAndFlags{}
+
+	// lsRemoteCmd represents the ls-remote command
+	lsRemoteCmd := &cobra.Command{
+		Use:        "ls-remote",
+		SuggestFor: []string{"lsremote", "ls-remot", "list-remote"},
+		Short:      "ls-remote lists the blobs of a container.",
+		Long: `ls-remote lists the blobs of a container, or the blobs whose name starts with the given prefix, e.g. https://account.blob.core.windows.net/container/prefix.
+The name, size, last modified time and blob type of each blob are printed. Without --recursive, the blobs inside virtual directories are not listed, the virtual directories are listed instead.
+The access tier of the blobs is not printed, since the 2016-05-31 service version in use cannot return it.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			// the only argument to this command should be the container to list
+			if len(args) != 1 {
+				return errors.New("this command requires the url of a container")
+			}
+			if determineLocaltionType(args[0]) != common.Blob {
+				return errors.New("the provided container url is invalid")
+			}
+			if commandLineInput.Output != "text" && commandLineInput.Output != "json" {
+				return errors.New("the output format should be either text or json")
+			}
+			if err := handlers.ValidateNamePatterns(commandLineInput.Include); err != nil {
+				return err
+			}
+			if err := handlers.ValidateNamePatterns(commandLineInput.Exclude); err != nil {
+				return err
+			}
+			commandLineInput.Source = args[0]
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			handlers.HandleListRemoteCommand(commandLineInput)
+		},
+	}
+
+	rootCmd.AddCommand(lsRemoteCmd)
+
+	// define the flags relevant to the ls-remote command
+
+	// filters
+	lsRemoteCmd.PersistentFlags().StringVar(&commandLineInput.Include, "include", "", "Filter: Include only the blobs and virtual directories matching these semicolon separated patterns when listing. Patterns with a / are matched against the relative path, ** matching any number of directories.")
+	lsRemoteCmd.PersistentFlags().StringVar(&commandLineInput.Exclude, "exclude", "", "Filter: Exclude the blobs and virtual directories matching these semicolon separated patterns when listing. Patterns are matched as with --include.")
+	lsRemoteCmd.PersistentFlags().BoolVar(&commandLineInput.Recursive, "recursive", false, "Filter: Look into virtual sub-directories recursively when listing.")
+
+	// options
+	lsRemoteCmd.PersistentFlags().StringVar(&commandLineInput.Output, "output", "text", "Format of the listing, either text or json.")
+}
@@ -0,0 +1,74 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/handlers"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	commandLineInput := common.PauseCmdArgsAndFlags{}
+
+	// pauseCmd represents the pause command
+	pauseCmd := &cobra.Command{
+		Use:        "pause",
+		SuggestFor: []string{"pase", "puse", "suspend"},
+		Short:      "pause pauses an existing job.",
+		Long: `pause stops handing the transfers of an existing job to the transfer engine's workers, freeing the bandwidth for other jobs.
+The chunks in flight complete, and the job continues from where it stopped once it is unpaused.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			// the only argument to this command should be the id of the job to pause
+			if len(args) != 1 {
+				return errors.New("this command requires the id of the job to pause")
+			}
+			commandLineInput.JobId = args[0]
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			handlers.HandlePauseCommand(commandLineInput)
+		},
+	}
+
+	// unpauseCmd represents the unpause command
+	unpauseCmd := &cobra.Command{
+		Use:        "unpause",
+		SuggestFor: []string{"unpase", "unpuse"},
+		Short:      "unpause continues a paused job.",
+		Long:       `unpause continues the transfers of a paused job from where they were paused.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			// the only argument to this command should be the id of the job to unpause
+			if len(args) != 1 {
+				return errors.New("this command requires the id of the job to unpause")
+			}
+			commandLineInput.JobId = args[0]
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			handlers.HandleUnpauseCommand(commandLineInput)
+		},
+	}
+
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(unpauseCmd)
+}
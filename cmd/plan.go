@@ -0,0 +1,71 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/handlers"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	showCommandLineInput := common.PlanShowCmdArgsAndFlags{}
+
+	// planCmd represents the plan command
+	planCmd := &cobra.Command{
+		Use:        "plan",
+		SuggestFor: []string{"pln", "plans"},
+		Short:      "plan inspects the job part plan files of the transfer engine.",
+		Long:       `plan inspects the job part plan files (.stev files) the transfer engine keeps for each part of a job.`,
+	}
+
+	// planShowCmd represents the plan show command
+	planShowCmd := &cobra.Command{
+		Use:        "show",
+		SuggestFor: []string{"shw", "dump", "print"},
+		Short:      "show decodes a job part plan file.",
+		Long: `show decodes a job part plan file offline and prints its header, blob data, transfers and chunks.
+The transfer engine does not need to be running, and the file is only read, so it can be inspected while a job is in progress.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			// the only argument to this command should be the job part plan file
+			if len(args) != 1 {
+				return errors.New("this command requires the path of a job part plan file")
+			}
+			if showCommandLineInput.Output != "text" && showCommandLineInput.Output != "json" {
+				return errors.New("the output format should be either text or json")
+			}
+			showCommandLineInput.FileName = args[0]
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			handlers.HandlePlanShowCommand(showCommandLineInput)
+		},
+	}
+
+	rootCmd.AddCommand(planCmd)
+	planCmd.AddCommand(planShowCmd)
+
+	// define the flags relevant to the plan show command
+
+	// options
+	planShowCmd.PersistentFlags().StringVar(&showCommandLineInput.Output, "output", "text", "Format of the decoded plan, either text or json.")
+}
@@ -21,33 +21,58 @@
 package cmd
 
 import (
-	"fmt"
-
+	"errors"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/handlers"
 	"github.com/spf13/cobra"
 )
 
-// deleteCmd represents the delete command
-var deleteCmd = &cobra.Command{
-	Use:   "remove",
-	Aliases: []string{"rm", "r"},
-	SuggestFor: []string{"delete", "del"},
-	Short: "Coming soon: remove(rm) deletes blobs or containers in Azure Storage.",
-	Long: `Coming soon: remove(rm) deletes blobs or containers in Azure Storage.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("delete called, but not implemented yet.")
-	},
-}
-
 func init() {
+	commandLineInput := common.RemoveCmdArgsAndFlags{}
+
+	// deleteCmd represents the delete command
+	deleteCmd := &cobra.Command{
+		Use:        "remove",
+		Aliases:    []string{"rm", "r"},
+		SuggestFor: []string{"delete", "del"},
+		Short:      "remove(rm) deletes blobs in Azure Storage.",
+		Long: `remove(rm) deletes blobs in Azure Storage. The most common cases are:
+  - Delete a single blob.
+  - Delete the blobs of a container, or of a virtual directory given with a trailing slash.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			// the only argument to this command should be the blob or container to delete
+			if len(args) != 1 {
+				return errors.New("this command requires the url of a blob or container")
+			}
+
+			if determineLocaltionType(args[0]) != common.Blob {
+				return errors.New("the provided source is invalid")
+			}
+			if err := handlers.ValidateNamePatterns(commandLineInput.Include); err != nil {
+				return err
+			}
+			if err := handlers.ValidateNamePatterns(commandLineInput.Exclude); err != nil {
+				return err
+			}
+
+			commandLineInput.Source = args[0]
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			handlers.HandleRemoveCommand(commandLineInput)
+		},
+	}
+
 	rootCmd.AddCommand(deleteCmd)
 
-	// Here you will define your flags and configuration settings.
+	// define the flags relevant to the remove command
 
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// deleteCmd.PersistentFlags().String("foo", "", "A help for foo")
+	// filters
+	deleteCmd.PersistentFlags().StringVar(&commandLineInput.Include, "include", "", "Filter: Include only the blobs matching these semicolon separated patterns when removing. Patterns with a / are matched against the relative path, ** matching any number of directories.")
+	deleteCmd.PersistentFlags().StringVar(&commandLineInput.Exclude, "exclude", "", "Filter: Exclude the blobs matching these semicolon separated patterns when removing. Patterns are matched as with --include.")
+	deleteCmd.PersistentFlags().BoolVar(&commandLineInput.Recursive, "recursive", false, "Filter: Look into virtual sub-directories recursively when removing from a container.")
 
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// deleteCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	// options
+	deleteCmd.PersistentFlags().BoolVar(&commandLineInput.IsaBackgroundOp, "background-op", false, "true if user has to perform the operations as a background operation")
+	deleteCmd.PersistentFlags().Uint8Var(&commandLineInput.LogVerbosity, "Logging level", uint8(common.LOG_DEBUG_LEVEL), "defines the log verbosity to be saved to log file")
 }
@@ -0,0 +1,60 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/handlers"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	commandLineInput := common.ResumeCmdArgsAndFlags{}
+
+	// resumeCmd represents the resume command
+	resumeCmd := &cobra.Command{
+		Use:        "resume",
+		SuggestFor: []string{"resme", "esume", "restart"},
+		Short:      "resume resumes an existing job.",
+		Long: `resume resumes an existing job, for example after the transfer engine crashed or the machine rebooted.
+The transfers of the job which are not complete yet are scheduled again, skipping the chunks which were already transferred.
+The transfer engine does not keep the credentials of S3 and GCS sources on disk, so they are read from the environment again, as copy does.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			// the only argument to this command should be the id of the job to resume
+			if len(args) != 1 {
+				return errors.New("this command requires the id of the job to resume")
+			}
+			commandLineInput.JobId = args[0]
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			handlers.HandleResumeCommand(commandLineInput)
+		},
+	}
+
+	rootCmd.AddCommand(resumeCmd)
+
+	// define the flags relevant to the resume command
+
+	// options
+	resumeCmd.PersistentFlags().BoolVar(&commandLineInput.IsaBackgroundOp, "background-op", false, "true if user has to perform the operations as a background operation")
+}
@@ -21,32 +21,56 @@
 package cmd
 
 import (
-	"fmt"
-
+	"errors"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/handlers"
 	"github.com/spf13/cobra"
 )
 
-// syncCmd represents the sync command
-var syncCmd = &cobra.Command{
-	Use:   "sync",
-	Aliases: []string{"sc", "s"},
-	Short: "Coming soon: sync replicates source to the destination location.",
-	Long: `Coming soon: sync replicates source to the destination location.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("sync called, but not implemented.")
-	},
-}
-
 func init() {
+	commandLineInput := common.SyncCmdArgsAndFlags{}
+
+	// syncCmd represents the sync command
+	syncCmd := &cobra.Command{
+		Use:     "sync",
+		Aliases: []string{"sc", "s"},
+		Short:   "sync replicates source to the destination location.",
+		Long: `sync replicates source to the destination location. Only the files which are new or changed are transferred. The supported cases are:
+  - Sync a local directory to a container, or a virtual directory inside it.
+  - Sync a container, or a virtual directory inside it, to a local directory.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			// the only arguments to this command should be a source and destination
+			if len(args) != 2 {
+				return errors.New("this command requires source and destination")
+			}
+
+			sourceType := determineLocaltionType(args[0])
+			destinationType := determineLocaltionType(args[1])
+			if !(sourceType == common.Local && destinationType == common.Blob || sourceType == common.Blob && destinationType == common.Local) {
+				return errors.New("the provided source/destination pair is invalid")
+			}
+
+			commandLineInput.Source = args[0]
+			commandLineInput.Destination = args[1]
+			commandLineInput.SourceType = sourceType
+			commandLineInput.DestinationType = destinationType
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			handlers.HandleSyncCommand(commandLineInput)
+		},
+	}
+
 	rootCmd.AddCommand(syncCmd)
 
-	// Here you will define your flags and configuration settings.
+	// define the flags relevant to the sync command
 
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// syncCmd.PersistentFlags().String("foo", "", "A help for foo")
+	// filters
+	syncCmd.PersistentFlags().BoolVar(&commandLineInput.Recursive, "recursive", false, "Filter: Look into sub-directories recursively when syncing.")
 
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// syncCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	// options
+	syncCmd.PersistentFlags().BoolVar(&commandLineInput.DeleteDestination, "delete-destination", false, "Delete the entities at the destination which do not exist at the source.")
+	syncCmd.PersistentFlags().Uint32Var(&commandLineInput.BlockSize, "block-size", 0, "Use this block size when uploading to Azure Storage.")
+	syncCmd.PersistentFlags().BoolVar(&commandLineInput.IsaBackgroundOp, "background-op", false, "true if user has to perform the operations as a background operation")
+	syncCmd.PersistentFlags().Uint8Var(&commandLineInput.LogVerbosity, "Logging level", uint8(common.LOG_DEBUG_LEVEL), "defines the log verbosity to be saved to log file")
 }
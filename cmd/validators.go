@@ -27,9 +27,23 @@ import (
 )
 
 func determineLocaltionType(stringToParse string) common.LocationType {
-	if IsLocalPath(stringToParse) {
+	if stringToParse == "-" {
+		return common.Pipe
+	} else if IsLocalPath(stringToParse) {
 		return common.Local
 	} else if IsUrl(stringToParse) {
+		// Azure Files, S3 and GCS urls are recognized by their host, or by lying under the custom endpoint used for local testing
+		// any other url is taken as Blob storage, plain http(s) sources are only read when asked for with --from-http
+		u, _ := url.Parse(stringToParse)
+		if common.IsFileUrl(*u) {
+			return common.File
+		}
+		if common.IsS3Url(*u) {
+			return common.S3
+		}
+		if common.IsGCSUrl(*u) {
+			return common.GCS
+		}
 		return common.Blob
 	} else {
 		return common.Unknown
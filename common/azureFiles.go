@@ -0,0 +1,217 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileEndpointEnvVar names the environment variable holding a custom Azure Files endpoint, for instance a local emulator
+// the endpoint includes the account when it is addressed by path, e.g. http://127.0.0.1:10004/devstoreaccount1
+const FileEndpointEnvVar = "AZS_FILE_ENDPOINT"
+
+// MaxFileRangeSize is the largest range which can be written to a file in Azure Files in a single request
+const MaxFileRangeSize = 4 * 1024 * 1024
+
+const fileServiceHostSuffix = ".file.core.windows.net"
+const fileServiceVersion = "2016-05-31"
+
+// IsFileUrl returns whether the url points to Azure Files, either in the public cloud or at the custom endpoint
+func IsFileUrl(u url.URL) bool {
+	if strings.HasSuffix(strings.ToLower(u.Host), fileServiceHostSuffix) {
+		return true
+	}
+	_, isCustomEndpoint := customEndpointPath(u, FileEndpointEnvVar)
+	return isCustomEndpoint
+}
+
+// SplitFileUrlPath splits the path of an Azure Files url into the path of the share and the path within the share
+// the path of the share keeps the account of a custom endpoint which is addressed by path
+func SplitFileUrlPath(u url.URL) (sharePath string, pathInShare string) {
+	endpointPath, _ := customEndpointPath(u, FileEndpointEnvVar)
+	pathParts := strings.SplitN(strings.TrimPrefix(strings.TrimPrefix(u.Path, endpointPath), "/"), "/", 2)
+	sharePath = endpointPath + "/" + pathParts[0]
+	if len(pathParts) > 1 {
+		pathInShare = pathParts[1]
+	}
+	return sharePath, pathInShare
+}
+
+// FileURL represents a file in Azure Files, the url carries the SAS granting access to it
+type FileURL struct {
+	url url.URL
+}
+
+// NewFileURL creates a FileURL for the given url
+func NewFileURL(u url.URL) FileURL {
+	return FileURL{url: u}
+}
+
+// Create creates the file with the given size, replacing the file if it exists already
+func (f FileURL) Create(ctx context.Context, size int64) error {
+	resp, err := doFileServiceRequest(ctx, http.MethodPut, f.url, nil, map[string]string{
+		"x-ms-type":           "file",
+		"x-ms-content-length": strconv.FormatInt(size, 10),
+	}, nil, http.StatusCreated)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// PutRange writes the body into the file starting at offset
+func (f FileURL) PutRange(ctx context.Context, offset int64, body []byte) error {
+	resp, err := doFileServiceRequest(ctx, http.MethodPut, f.url, url.Values{"comp": {"range"}}, map[string]string{
+		"x-ms-range": fmt.Sprintf("bytes=%d-%d", offset, offset+int64(len(body))-1),
+		"x-ms-write": "update",
+	}, body, http.StatusCreated)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// GetRange returns the body holding count bytes of the file starting at offset, the caller closes it
+func (f FileURL) GetRange(ctx context.Context, offset int64, count int64) (io.ReadCloser, error) {
+	resp, err := doFileServiceRequest(ctx, http.MethodGet, f.url, nil, map[string]string{
+		"x-ms-range": fmt.Sprintf("bytes=%d-%d", offset, offset+count-1),
+	}, nil, http.StatusPartialContent, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// GetProperties returns the size and the last modified time of the file
+func (f FileURL) GetProperties(ctx context.Context) (size int64, lastModified time.Time, err error) {
+	resp, err := doFileServiceRequest(ctx, http.MethodHead, f.url, nil, nil, nil, http.StatusOK)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	resp.Body.Close()
+	lastModified, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+	return resp.ContentLength, lastModified, nil
+}
+
+// DirectoryURL represents a directory in Azure Files, the url carries the SAS granting access to it
+type DirectoryURL struct {
+	url url.URL
+}
+
+// NewDirectoryURL creates a DirectoryURL for the given url
+func NewDirectoryURL(u url.URL) DirectoryURL {
+	return DirectoryURL{url: u}
+}
+
+// CreateIfNotExists creates the directory, a directory which exists already is left as is
+func (d DirectoryURL) CreateIfNotExists(ctx context.Context) error {
+	resp, err := doFileServiceRequest(ctx, http.MethodPut, d.url, url.Values{"restype": {"directory"}}, nil, nil, http.StatusCreated, http.StatusConflict)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// ListFilesAndDirectoriesResponse is a segment of the files and directories listed in a directory
+type ListFilesAndDirectoriesResponse struct {
+	Files []struct {
+		Name       string `xml:"Name"`
+		Properties struct {
+			ContentLength int64 `xml:"Content-Length"`
+		} `xml:"Properties"`
+	} `xml:"Entries>File"`
+	Directories []struct {
+		Name string `xml:"Name"`
+	} `xml:"Entries>Directory"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+// ListFilesAndDirectories lists a segment of the files and directories of the directory, starting at the marker
+// the listing is complete once the NextMarker of a segment is empty
+func (d DirectoryURL) ListFilesAndDirectories(ctx context.Context, marker string) (*ListFilesAndDirectoriesResponse, error) {
+	query := url.Values{"restype": {"directory"}, "comp": {"list"}}
+	if marker != "" {
+		query.Set("marker", marker)
+	}
+	resp, err := doFileServiceRequest(ctx, http.MethodGet, d.url, query, nil, nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	listResponse := &ListFilesAndDirectoriesResponse{}
+	err = xml.NewDecoder(resp.Body).Decode(listResponse)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the listing of directory %s with err %s", d.url.Path, err.Error())
+	}
+	return listResponse, nil
+}
+
+// doFileServiceRequest sends a request to Azure Files and returns the response if its status is one of the expected ones
+// the query parameters are added to the ones of the url, which carry the SAS
+func doFileServiceRequest(ctx context.Context, method string, u url.URL, query url.Values, headers map[string]string,
+	body []byte, expectedStatusCodes ...int) (*http.Response, error) {
+	requestQuery := u.Query()
+	for key, values := range query {
+		requestQuery[key] = values
+	}
+	u.RawQuery = requestQuery.Encode()
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("x-ms-version", fileServiceVersion)
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	for _, statusCode := range expectedStatusCodes {
+		if resp.StatusCode == statusCode {
+			return resp, nil
+		}
+	}
+
+	// the url is left out of the error since its query holds the SAS
+	errorBody, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	return nil, FileServiceError{
+		StatusCode: resp.StatusCode,
+		ErrorCode:  resp.Header.Get("x-ms-error-code"),
+		message:    fmt.Sprintf("%s %s failed with status %s: %s", method, u.Path, resp.Status, string(errorBody)),
+	}
+}
+
+// FileServiceError is returned when Azure Files responds with another status than the expected ones
+type FileServiceError struct {
+	StatusCode int
+	ErrorCode  string // the error code of the response, which responses to HEAD requests only carry in their headers
+	message    string
+}
+
+func (e FileServiceError) Error() string {
+	return e.message
+}
+
+// IsFileServiceResourceNotFound returns whether the error tells that the file or directory requested does not exist
+func IsFileServiceResourceNotFound(err error) bool {
+	fileServiceError, ok := err.(FileServiceError)
+	return ok && fileServiceError.StatusCode == http.StatusNotFound && fileServiceError.ErrorCode == "ResourceNotFound"
+}
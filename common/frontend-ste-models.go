@@ -36,9 +36,10 @@ type CopyCmdArgsAndFlags struct {
 	Source      string
 	Destination string
 
-	// inferred from arguments
+	// inferred from arguments, a source url is read over plain http(s) only when FromHttp is set
 	SourceType LocationType
 	DestinationType LocationType
+	FromHttp        bool
 
 	// filters from flags
 	Include        string
@@ -46,6 +47,7 @@ type CopyCmdArgsAndFlags struct {
 	Recursive      bool
 	FollowSymlinks bool
 	WithSnapshots  bool
+	ListOfFiles    string
 
 	// options from flags
 	BlockSize                uint32
@@ -59,6 +61,109 @@ type CopyCmdArgsAndFlags struct {
 	IsaBackgroundOp          bool
 	Acl                      string
 	LogVerbosity             uint8
+
+	// the local tree is uploaded as a single archive blob, and an archive blob is extracted when downloaded
+	Archive string
+	Gzip    bool
+	Extract bool
+}
+
+// SyncCmdArgsAndFlags represents the raw sync command input from the user
+type SyncCmdArgsAndFlags struct {
+	// from arguments
+	Source      string
+	Destination string
+
+	// inferred from arguments
+	SourceType      LocationType
+	DestinationType LocationType
+
+	// filters from flags
+	Recursive bool
+
+	// options from flags
+	DeleteDestination bool
+	BlockSize         uint32
+	IsaBackgroundOp   bool
+	LogVerbosity      uint8
+}
+
+// RemoveCmdArgsAndFlags represents the raw remove command input from the user
+type RemoveCmdArgsAndFlags struct {
+	// from arguments
+	Source string
+
+	// filters from flags
+	Include   string
+	Exclude   string
+	Recursive bool
+
+	// options from flags
+	IsaBackgroundOp bool
+	LogVerbosity    uint8
+}
+
+// ResumeCmdArgsAndFlags represents the raw resume command input from the user
+type ResumeCmdArgsAndFlags struct {
+	JobId           string
+	IsaBackgroundOp bool
+}
+
+// ResumeJobOrder represents the body of the request resuming a job
+// the credentials of S3 and GCS sources are not part of the job part plan, so they are given again by the front-end
+type ResumeJobOrder struct {
+	S3Credentials  S3Credentials
+	GCSCredentials S3Credentials
+}
+
+// CancelCmdArgsAndFlags represents the raw cancel command input from the user
+type CancelCmdArgsAndFlags struct {
+	JobId string
+}
+
+// PauseCmdArgsAndFlags represents the raw pause and unpause command input from the user
+type PauseCmdArgsAndFlags struct {
+	JobId string
+}
+
+// JobsCleanCmdArgsAndFlags represents the raw jobs clean command input from the user
+type JobsCleanCmdArgsAndFlags struct {
+	JobId     string
+	OlderThan *time.Duration // nil when not given, the jobs are then removed once their time to live has passed
+}
+
+// BenchCmdArgsAndFlags represents the raw bench command input from the user
+type BenchCmdArgsAndFlags struct {
+	ContainerUrl string
+
+	// options
+	FileCount       uint32
+	FileSize        int64
+	InMemory        bool
+	Download        bool
+	BlockSize       uint32
+	SweepBlockSizes string
+	SweepWorkers    string
+	LogVerbosity    uint8
+}
+
+// ListRemoteCmdArgsAndFlags represents the raw ls-remote command input from the user
+type ListRemoteCmdArgsAndFlags struct {
+	Source string
+
+	// filters
+	Include   string
+	Exclude   string
+	Recursive bool
+
+	// options
+	Output string
+}
+
+// PlanShowCmdArgsAndFlags represents the raw plan show command input from the user
+type PlanShowCmdArgsAndFlags struct {
+	FileName string
+	Output   string
 }
 
 // ListCmdArgsAndFlags represents the raw list command input from the user
@@ -73,8 +178,74 @@ const (
 	Local LocationType = 0
 	Blob LocationType = 1
 	Unknown LocationType = 2
+	Delete LocationType = 3 // used as destination type when the transfers of a job delete their source
+	File LocationType = 4 // a share of Azure Files
+	S3 LocationType = 5 // a bucket of S3, or of an S3-compatible object storage
+	GCS LocationType = 6 // a bucket of Google Cloud Storage
+	Http LocationType = 7 // any other http(s) url, which can only be read from
+	Pipe LocationType = 8 // the standard input or output of azs, given as -
+)
+
+// String returns the name of the location type
+func (locationType LocationType) String() string {
+	switch locationType {
+	case Local:
+		return "Local"
+	case Blob:
+		return "Blob"
+	case Delete:
+		return "Delete"
+	case File:
+		return "File"
+	case S3:
+		return "S3"
+	case GCS:
+		return "GCS"
+	case Http:
+		return "Http"
+	case Pipe:
+		return "Pipe"
+	default:
+		return "Unknown"
+	}
+}
+
+// BlobType represents the type of the blobs which local files are uploaded as
+type BlobType uint8
+const (
+	BlockBlob BlobType = 0
+	PageBlob  BlobType = 1
+	AppendBlob BlobType = 2
 )
 
+// String returns the name of the blob type, as accepted by the blob-type flag
+func (blobType BlobType) String() string {
+	switch blobType {
+	case BlockBlob:
+		return "block"
+	case PageBlob:
+		return "page"
+	case AppendBlob:
+		return "append"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseBlobType returns the blob type for the given value of the blob-type flag
+func ParseBlobType(blobType string) (BlobType, error) {
+	switch blobType {
+	case "", "block":
+		return BlockBlob, nil
+	case "page":
+		return PageBlob, nil
+	case "append":
+		return AppendBlob, nil
+	default:
+		return BlockBlob, fmt.Errorf("invalid blob type %s. Valid blob types are block, page, append", blobType)
+	}
+}
+
 // This struct represent a single transfer entry with source and destination details
 type CopyTransfer struct {
 	Source           string
@@ -93,6 +264,8 @@ type CopyJobPartOrder struct {
 	SourceType         LocationType
 	DestinationType    LocationType
 	Transfers          []CopyTransfer
+	NumFilteredEntities uint32 // number of entities left out of this part by the include/exclude filters
+	SourceCredentials  S3Credentials // credentials of an S3 or GCS source, the transfer engine keeps them in memory only
 	LogVerbosity       LogSeverity
 	IsaBackgroundOp    bool
 	OptionalAttributes BlobTransferAttributes
@@ -102,6 +275,7 @@ type CopyJobPartOrder struct {
 type ListJobPartsTransfers struct{
 	JobId		JobID
 	ExpectedTransferStatus Status
+	WithChunkLatencies bool // whether the summary of the job includes the percentiles of the latencies of its chunks
 }
 
 // This struct represents the optional attribute for blob request header
@@ -112,6 +286,7 @@ type BlobTransferAttributes struct {
 	NoGuessMimeType          bool // represents user decision to interpret the content-encoding from source file
 	PreserveLastModifiedTime bool // when downloading, tell engine to set file's timestamp to timestamp of blob
 	BlockSizeinBytes         uint32
+	BlobType                 BlobType // type of the blobs the local files are uploaded as
 }
 
 // ExistingJobDetails represent the Job with JobId and
@@ -126,11 +301,56 @@ type JobProgressSummary struct {
 	TotalNumberOfTransfer                    uint32
 	TotalNumberofTransferCompleted           uint32
 	TotalNumberofFailedTransfer				 uint32
+	TotalNumberofTransferCancelled           uint32
+	TotalNumberOfFilteredEntities            uint32 // entities left out of the job by the include/exclude filters
 	//NumberOfTransferCompletedafterCheckpoint uint32
 	//NumberOfTransferFailedAfterCheckpoint    uint32
 	PercentageProgress                       uint32
 	FailedTransfers                          []TransferStatus
 	ThroughputInBytesPerSeconds				 float64
+	NumberOfChunksTransferred                uint32
+	ChunkLatencyP50InMilliseconds            float64
+	ChunkLatencyP90InMilliseconds            float64
+	ChunkLatencyP99InMilliseconds            float64
+}
+
+// JobPartPlanDetails represents the content of a job part plan file decoded by the plan show command
+type JobPartPlanDetails struct {
+	FileName            string
+	Version             uint32
+	JobId               JobID
+	PartNum             uint32
+	IsFinalPart         bool
+	Priority            uint8
+	TTLAfterCompletion  uint32
+	SrcLocationType     LocationType
+	DstLocationType     LocationType
+	NumTransfers        uint32
+	NumFilteredEntities uint32
+	IsCancelled         bool
+	ContentType         string
+	ContentEncoding     string
+	Metadata            string
+	BlockSize           uint64
+	BlobType            BlobType
+	Transfers           []JobPartPlanTransferDetails
+}
+
+// JobPartPlanTransferDetails represents a transfer of a decoded job part plan file
+type JobPartPlanTransferDetails struct {
+	Src            string
+	Dst            string
+	Status         string
+	SourceSize     uint64
+	ModifiedTime   int64
+	CompletionTime uint64
+	Chunks         []JobPartPlanChunkDetails
+}
+
+// JobPartPlanChunkDetails represents a chunk of a transfer of a decoded job part plan file
+type JobPartPlanChunkDetails struct {
+	Status  string
+	BlockId string
 }
 
 // represents the Status and details of a single transfer
@@ -148,6 +368,7 @@ type TransfersStatus struct {
 const (
 	StatusCompleted  = 1
 	StatusInProgress = 2
+	StatusCancelled  = 3
 )
 
 // These constants defines the various states of transfer
@@ -155,7 +376,8 @@ const (
 	TransferStatusActive = 0  // Active Transfers
 	TransferStatusComplete = 1 // Completed Transfers
 	TransferStatusFailed = 2 // Failed Transfers
-	TranferStatusAll = 254  // All types of Transfer (Active | Complete | Failed)
+	TransferStatusCancelled = 3 // Cancelled Transfers
+	TranferStatusAll = 254  // All types of Transfer (Active | Complete | Failed | Cancelled)
 )
 
 // TransferStatusStringToStatusCode returns the Transfer Status Code given for Transfer Status
@@ -167,10 +389,12 @@ func TransferStatusStringToStatusCode(status string) (Status){
 		return 1
 	case "TransferStatusFailed" :
 		return 2
+	case "TransferStatusCancelled":
+		return 3
 	case "TranferStatusAll":
 		return 254
 	default:
-		panic(errors.New(fmt.Sprintf("invalid expected transfer status %s. Valid status are TransferStatusActive, TransferStatusComplete, TransferStatusFailed TranferStatusAll", status)))
+		panic(errors.New(fmt.Sprintf("invalid expected transfer status %s. Valid status are TransferStatusActive, TransferStatusComplete, TransferStatusFailed, TransferStatusCancelled, TranferStatusAll", status)))
 	}
 }
 
@@ -183,10 +407,24 @@ func TransferStatusCodeToString(status Status) (string) {
 		return "TransferStatusComplete"
 	case 2:
 		return "TransferStatusFailed"
-	case 255:
+	case 3:
+		return "TransferStatusCancelled"
+	case 254:
 		return "TranferStatusAll"
 	default:
-		panic(errors.New(fmt.Sprintf("invalid expected transfer status code %d. Valid status are 0, 1, 2, 255", status)))
+		panic(errors.New(fmt.Sprintf("invalid expected transfer status code %d. Valid status are 0, 1, 2, 3, 254", status)))
 	}
 }
-const DefaultBlockSize = 4 * 1024 * 1024
\ No newline at end of file
+const DefaultBlockSize = 4 * 1024 * 1024
+
+// PageSize is the size of a page of page blobs, the size and the writes of a page blob are aligned to it
+const PageSize = 512
+
+// MaxPageBlobChunkSize is the largest range of pages which can be written to a page blob in a single request
+const MaxPageBlobChunkSize = 4 * 1024 * 1024
+
+// MaxAppendBlockSize is the largest block which can be appended to an append blob in a single request
+const MaxAppendBlockSize = 4 * 1024 * 1024
+
+// DefaultNumOfEngineWorkers is the number of workers the execution engine of the transfer engine starts with
+const DefaultNumOfEngineWorkers = 5
\ No newline at end of file
@@ -0,0 +1,85 @@
+package common
+
+import (
+	"net/url"
+	"os"
+	"strings"
+)
+
+// GCSEndpointEnvVar names the environment variable holding a custom GCS endpoint, for instance a local stand-in
+// buckets at the custom endpoint are addressed by path, e.g. http://127.0.0.1:4443/bucket/object
+const GCSEndpointEnvVar = "AZS_GCS_ENDPOINT"
+
+// the HMAC key of a service account, with which the requests to the XML API of GCS are signed, is read by the front-end
+// requests are sent anonymously when no access key is set, which is enough for public buckets
+const (
+	gcsAccessKeyIdEnvVar = "GCS_ACCESS_KEY_ID"
+	gcsSecretEnvVar      = "GCS_SECRET_ACCESS_KEY"
+)
+
+const gcsHost = "storage.googleapis.com"
+
+// GCS signs requests for the region auto, rather than for the region of the bucket
+const gcsRegion = "auto"
+
+// IsGCSUrl returns whether the url points to GCS, either in the public cloud, as a gs:// url or at the custom endpoint
+func IsGCSUrl(u url.URL) bool {
+	host := strings.ToLower(u.Host)
+	if u.Scheme == "gs" || host == gcsHost || strings.HasSuffix(host, "."+gcsHost) {
+		return true
+	}
+	_, isCustomEndpoint := customEndpointPath(u, GCSEndpointEnvVar)
+	return isCustomEndpoint
+}
+
+// ParseGCSURL splits a GCS url into its parts, the objects of GCS are reached through its S3-compatible XML API
+// gs://bucket/object urls are addressed by path at the public endpoint
+func ParseGCSURL(u url.URL) S3URLParts {
+	parts := S3URLParts{Scheme: u.Scheme, Host: u.Host, Region: gcsRegion, pathStyle: true}
+	objectPath := u.Path
+	host := strings.ToLower(u.Host)
+	endpointPath, isCustomEndpoint := customEndpointPath(u, GCSEndpointEnvVar)
+	switch {
+	case u.Scheme == "gs":
+		parts.Scheme = "https"
+		parts.Host = gcsHost
+		objectPath = "/" + u.Host + u.Path
+	case isCustomEndpoint:
+		parts.endpointPath = endpointPath
+		objectPath = strings.TrimPrefix(u.Path, endpointPath)
+	case strings.HasSuffix(host, "."+gcsHost):
+		// virtual hosted style, e.g. bucket.storage.googleapis.com/object
+		parts.pathStyle = false
+		parts.Bucket = u.Host[:len(u.Host)-len("."+gcsHost)]
+	}
+
+	objectPath = strings.TrimPrefix(objectPath, "/")
+	if parts.pathStyle {
+		pathParts := strings.SplitN(objectPath, "/", 2)
+		parts.Bucket = pathParts[0]
+		if len(pathParts) > 1 {
+			parts.Key = pathParts[1]
+		}
+	} else {
+		parts.Key = objectPath
+	}
+	return parts
+}
+
+// GetGCSCredentialsFromEnvironment returns the HMAC key found in the environment
+func GetGCSCredentialsFromEnvironment() S3Credentials {
+	return S3Credentials{
+		AccessKeyId:     os.Getenv(gcsAccessKeyIdEnvVar),
+		SecretAccessKey: os.Getenv(gcsSecretEnvVar),
+	}
+}
+
+// NewGCSClient creates an S3Client for the XML API of GCS, with the given HMAC key
+func NewGCSClient(credentials S3Credentials) S3Client {
+	return S3Client{
+		credentials:          credentials,
+		scheme:               googSignatureV4,
+		service:              "storage",
+		metadataHeaderPrefix: "X-Goog-Meta-",
+	}
+}
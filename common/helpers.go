@@ -4,6 +4,9 @@ import (
 	"io"
 	"fmt"
 	"crypto/rand"
+	"net/url"
+	"os"
+	"strings"
 )
 
 // NewUUID generates a random UUID according to RFC 4122
@@ -19,3 +22,21 @@ func NewUUID() (string, error) {
 	uuid[6] = uuid[6]&^0xf0 | 0x40
 	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:]), nil
 }
+
+// customEndpointPath returns the path of the custom endpoint set in the given environment variable, if the url lies under it
+// custom endpoints let the services be replaced by local stand-ins for testing
+func customEndpointPath(u url.URL, endpointEnvVar string) (string, bool) {
+	customEndpoint := os.Getenv(endpointEnvVar)
+	if customEndpoint == "" {
+		return "", false
+	}
+	endpointUrl, err := url.Parse(customEndpoint)
+	if err != nil || endpointUrl.Host != u.Host || endpointUrl.Scheme != u.Scheme {
+		return "", false
+	}
+	endpointPath := strings.TrimSuffix(endpointUrl.Path, "/")
+	if !strings.HasPrefix(u.Path, endpointPath+"/") {
+		return "", false
+	}
+	return endpointPath, true
+}
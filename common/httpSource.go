@@ -0,0 +1,82 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HttpSourceProperties represents the properties of a plain http(s) source returned by a HEAD request
+type HttpSourceProperties struct {
+	Size         int64 // -1 when the server does not tell the length of the content
+	LastModified time.Time
+	ContentType  string
+
+	// whether the server announced that it serves byte ranges of the content
+	AcceptsRanges bool
+}
+
+// GetHttpSourceProperties sends a HEAD request to the url and returns the properties of the content it serves
+func GetHttpSourceProperties(ctx context.Context, u url.URL) (HttpSourceProperties, error) {
+	resp, err := doHttpSourceRequest(ctx, http.MethodHead, u, nil, http.StatusOK)
+	if err != nil {
+		return HttpSourceProperties{}, err
+	}
+	resp.Body.Close()
+	lastModified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return HttpSourceProperties{
+		Size:          resp.ContentLength,
+		LastModified:  lastModified,
+		ContentType:   resp.Header.Get("Content-Type"),
+		AcceptsRanges: strings.EqualFold(strings.TrimSpace(resp.Header.Get("Accept-Ranges")), "bytes"),
+	}, nil
+}
+
+// GetHttpSourceRange returns the body holding count bytes of the content starting at offset, the caller closes it
+// a server which ignores the range and sends the whole content fails the request, rather than being read from the start
+func GetHttpSourceRange(ctx context.Context, u url.URL, offset int64, count int64) (io.ReadCloser, error) {
+	resp, err := doHttpSourceRequest(ctx, http.MethodGet, u,
+		map[string]string{"Range": fmt.Sprintf("bytes=%d-%d", offset, offset+count-1)}, http.StatusPartialContent)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// GetHttpSource returns the body holding the whole content, the caller closes it
+func GetHttpSource(ctx context.Context, u url.URL) (io.ReadCloser, error) {
+	resp, err := doHttpSourceRequest(ctx, http.MethodGet, u, nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// doHttpSourceRequest sends a request without body to the url and returns the response if its status is the expected one
+func doHttpSourceRequest(ctx context.Context, method string, u url.URL, headers map[string]string, expectedStatusCode int) (*http.Response, error) {
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == expectedStatusCode {
+		return resp, nil
+	}
+
+	// the url is left out of the error since its query may hold a token
+	errorBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	return nil, fmt.Errorf("%s %s failed with status %s: %s", method, u.Path, resp.Status, string(errorBody))
+}
@@ -0,0 +1,336 @@
+package common
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// S3EndpointEnvVar names the environment variable holding a custom S3 endpoint, for instance a local S3-compatible server
+// buckets at the custom endpoint are addressed by path, e.g. http://127.0.0.1:9000/bucket/key
+const S3EndpointEnvVar = "AZS_S3_ENDPOINT"
+
+// the front-end reads the credentials from the environment variables the AWS tools use
+// requests are sent anonymously when no access key is set, which is enough for public buckets
+const (
+	s3AccessKeyIdEnvVar     = "AWS_ACCESS_KEY_ID"
+	s3SecretAccessKeyEnvVar = "AWS_SECRET_ACCESS_KEY"
+	s3SessionTokenEnvVar    = "AWS_SESSION_TOKEN"
+)
+
+const s3HostSuffix = ".amazonaws.com"
+
+// requests to a bucket whose region is not known yet are signed for us-east-1, S3 then tells the region of the bucket
+// in the x-amz-bucket-region header of its response
+const s3ProbeRegion = "us-east-1"
+const s3BucketRegionHeader = "X-Amz-Bucket-Region"
+
+// s3BucketRegions remembers the regions of the buckets looked up from the responses of S3, keyed by host and bucket
+var s3BucketRegions sync.Map
+
+// S3Credentials are the credentials the objects of an S3-compatible source are read with
+// the front-end reads them from its environment and passes them to the transfer engine in the job order
+type S3Credentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string // only set for temporary credentials
+}
+
+// GetS3CredentialsFromEnvironment returns the credentials found in the environment variables the AWS tools use
+func GetS3CredentialsFromEnvironment() S3Credentials {
+	return S3Credentials{
+		AccessKeyId:     os.Getenv(s3AccessKeyIdEnvVar),
+		SecretAccessKey: os.Getenv(s3SecretAccessKeyEnvVar),
+		SessionToken:    os.Getenv(s3SessionTokenEnvVar),
+	}
+}
+
+// IsS3Url returns whether the url points to S3, either in the public cloud or at the custom endpoint
+func IsS3Url(u url.URL) bool {
+	if _, _, isS3Host := s3HostStyle(strings.ToLower(u.Host)); isS3Host {
+		return true
+	}
+	_, isCustomEndpoint := customEndpointPath(u, S3EndpointEnvVar)
+	return isCustomEndpoint
+}
+
+// s3HostStyle returns whether the host of the public cloud addresses the bucket by path, as s3.us-west-2.amazonaws.com
+// does, or in the host, as bucket.s3.us-west-2.amazonaws.com does, in which case the bucket ends at bucketEnd
+// isS3Host is false for the hosts that are not the ones of S3
+func s3HostStyle(host string) (pathStyle bool, bucketEnd int, isS3Host bool) {
+	if !strings.HasSuffix(host, s3HostSuffix) {
+		return false, 0, false
+	}
+	if strings.HasPrefix(host, "s3.") || strings.HasPrefix(host, "s3-") {
+		return true, 0, true
+	}
+	bucketEnd = strings.LastIndex(host, ".s3")
+	if bucketEnd <= 0 || (host[bucketEnd+len(".s3")] != '.' && host[bucketEnd+len(".s3")] != '-') {
+		return false, 0, false
+	}
+	return false, bucketEnd, true
+}
+
+// S3URLParts represents the bucket and the key an S3 url points to, along with the endpoint serving the bucket
+// Region is empty when the url does not name it, it is then looked up from the responses of S3
+type S3URLParts struct {
+	Scheme string
+	Host   string
+	Bucket string
+	Key    string
+	Region string
+
+	// buckets are addressed by path, rather than by host, at the path of the endpoint
+	pathStyle    bool
+	endpointPath string
+}
+
+// ParseS3URL splits an S3 url into its parts, both virtual hosted and path style urls are supported
+// an error is returned if the url does not point to S3
+func ParseS3URL(u url.URL) (S3URLParts, error) {
+	parts := S3URLParts{Scheme: u.Scheme, Host: u.Host}
+	host := strings.ToLower(u.Host)
+	endpointPath, isCustomEndpoint := customEndpointPath(u, S3EndpointEnvVar)
+	pathStyle, bucketEnd, isS3Host := s3HostStyle(host)
+	switch {
+	case isCustomEndpoint:
+		parts.pathStyle = true
+		parts.endpointPath = endpointPath
+	case !isS3Host:
+		return S3URLParts{}, fmt.Errorf("%s is not an S3 url", u.String())
+	case pathStyle:
+		// path style, e.g. s3.us-west-2.amazonaws.com/bucket/key
+		parts.pathStyle = true
+		parts.Region = regionOfS3Host(host[len("s3")+1:])
+	default:
+		// virtual hosted style, e.g. bucket.s3.us-west-2.amazonaws.com/key
+		parts.Bucket = u.Host[:bucketEnd]
+		parts.Region = regionOfS3Host(host[bucketEnd+len(".s3")+1:])
+	}
+
+	objectPath := strings.TrimPrefix(strings.TrimPrefix(u.Path, parts.endpointPath), "/")
+	if parts.pathStyle {
+		pathParts := strings.SplitN(objectPath, "/", 2)
+		parts.Bucket = pathParts[0]
+		if len(pathParts) > 1 {
+			parts.Key = pathParts[1]
+		}
+	} else {
+		parts.Key = objectPath
+	}
+	return parts, nil
+}
+
+// regionOfS3Host returns the region named by the rest of an S3 host after "s3.", such as "us-west-2.amazonaws.com"
+func regionOfS3Host(hostRest string) string {
+	region := strings.TrimSuffix(hostRest, s3HostSuffix)
+	if region == "" || region == hostRest || region == "amazonaws.com" || strings.Contains(region, ".") {
+		return ""
+	}
+	return region
+}
+
+// inRegion returns the parts signed for the given region
+// buckets addressed by path at a public endpoint of S3 are addressed at the endpoint of the region instead, since the
+// other endpoints redirect to it
+func (parts S3URLParts) inRegion(region string) S3URLParts {
+	parts.Region = region
+	if parts.pathStyle && parts.endpointPath == "" && strings.HasSuffix(strings.ToLower(parts.Host), s3HostSuffix) {
+		parts.Host = "s3." + region + s3HostSuffix
+	}
+	return parts
+}
+
+// URL returns the url of the object with the key of the parts, or of the bucket if the key is empty
+func (parts S3URLParts) URL() url.URL {
+	u := url.URL{Scheme: parts.Scheme, Host: parts.Host}
+	if parts.pathStyle {
+		u.Path = parts.endpointPath + "/" + parts.Bucket
+		if parts.Key != "" {
+			u.Path += "/" + parts.Key
+		}
+	} else {
+		u.Path = "/" + parts.Key
+	}
+	return u
+}
+
+// S3Client sends requests to S3, or to another S3-compatible API, signed with the credentials it is created with
+type S3Client struct {
+	credentials S3Credentials
+
+	// the flavor of the signature, the service signed for and the prefix of the headers holding custom metadata
+	scheme               signatureV4Scheme
+	service              string
+	metadataHeaderPrefix string
+}
+
+// NewS3Client creates an S3Client with the given credentials
+func NewS3Client(credentials S3Credentials) S3Client {
+	return S3Client{
+		credentials:          credentials,
+		scheme:               awsSignatureV4,
+		service:              "s3",
+		metadataHeaderPrefix: "X-Amz-Meta-",
+	}
+}
+
+// S3ObjectProperties represents the properties of an object returned by a HEAD request
+type S3ObjectProperties struct {
+	Size         int64
+	LastModified time.Time
+	ContentType  string
+	Metadata     map[string]string // the custom metadata of the object, keyed by name without the header prefix
+}
+
+// GetObjectProperties returns the properties of the object the parts point to
+func (client S3Client) GetObjectProperties(ctx context.Context, object S3URLParts) (S3ObjectProperties, error) {
+	resp, err := client.do(ctx, http.MethodHead, object, nil, nil, http.StatusOK)
+	if err != nil {
+		return S3ObjectProperties{}, err
+	}
+	resp.Body.Close()
+	lastModified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	metadata := map[string]string{}
+	for name := range resp.Header {
+		if strings.HasPrefix(name, client.metadataHeaderPrefix) {
+			metadata[strings.ToLower(strings.TrimPrefix(name, client.metadataHeaderPrefix))] = resp.Header.Get(name)
+		}
+	}
+	return S3ObjectProperties{Size: resp.ContentLength, LastModified: lastModified, ContentType: resp.Header.Get("Content-Type"), Metadata: metadata}, nil
+}
+
+// GetObjectRange returns the body holding count bytes of the object starting at offset, the caller closes it
+func (client S3Client) GetObjectRange(ctx context.Context, object S3URLParts, offset int64, count int64) (io.ReadCloser, error) {
+	resp, err := client.do(ctx, http.MethodGet, object, nil,
+		map[string]string{"Range": fmt.Sprintf("bytes=%d-%d", offset, offset+count-1)}, http.StatusPartialContent, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// ListObjectsV2Response is a page of the objects listed in a bucket
+type ListObjectsV2Response struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// ListObjectsV2 lists a page of the objects of the bucket whose key starts with prefix, starting at the continuation token
+// the listing is complete once a page is not truncated
+func (client S3Client) ListObjectsV2(ctx context.Context, bucket S3URLParts, prefix string, delimiter string, continuationToken string) (*ListObjectsV2Response, error) {
+	bucket.Key = ""
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	if delimiter != "" {
+		query.Set("delimiter", delimiter)
+	}
+	if continuationToken != "" {
+		query.Set("continuation-token", continuationToken)
+	}
+	resp, err := client.do(ctx, http.MethodGet, bucket, query, nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	listResponse := &ListObjectsV2Response{}
+	err = xml.NewDecoder(resp.Body).Decode(listResponse)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the listing of bucket %s with err %s", bucket.Bucket, err.Error())
+	}
+	return listResponse, nil
+}
+
+// do sends a signed request to the S3-compatible API and returns the response if its status is one of the expected ones
+// a request to a bucket whose region is not known is sent again signed for the region S3 tells in its response
+func (client S3Client) do(ctx context.Context, method string, parts S3URLParts, query url.Values, headers map[string]string,
+	expectedStatusCodes ...int) (*http.Response, error) {
+	bucketKey := parts.Host + "/" + parts.Bucket
+	if parts.Region == "" {
+		if region, ok := s3BucketRegions.Load(bucketKey); ok {
+			parts = parts.inRegion(region.(string))
+		}
+	}
+	resp, err := client.send(ctx, method, parts, query, headers)
+	if err != nil {
+		return nil, err
+	}
+	// S3 answers a request signed for, or sent to, another region than the one of the bucket with a redirect or a bad request
+	if !isExpectedStatusCode(resp.StatusCode, expectedStatusCodes) && parts.Region == "" {
+		bucketRegion := resp.Header.Get(s3BucketRegionHeader)
+		if bucketRegion != "" {
+			resp.Body.Close()
+			s3BucketRegions.Store(bucketKey, bucketRegion)
+			resp, err = client.send(ctx, method, parts.inRegion(bucketRegion), query, headers)
+			if err != nil {
+				return nil, err
+			}
+		} else if resp.StatusCode == http.StatusMovedPermanently || resp.StatusCode == http.StatusBadRequest {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%s %s failed with status %s and the region of bucket %s is unknown, please name the region in the url, e.g. https://%s.s3.<region>.amazonaws.com",
+				method, parts.URL().Path, resp.Status, parts.Bucket, parts.Bucket)
+		}
+	}
+	if isExpectedStatusCode(resp.StatusCode, expectedStatusCodes) {
+		return resp, nil
+	}
+
+	errorBody, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	return nil, fmt.Errorf("%s %s failed with status %s: %s", method, parts.URL().Path, resp.Status, string(errorBody))
+}
+
+// send sends a request to the S3-compatible API, signed with the credentials of the client if it has any
+// a request to a bucket whose region is not known is signed for the probe region
+func (client S3Client) send(ctx context.Context, method string, parts S3URLParts, query url.Values, headers map[string]string) (*http.Response, error) {
+	u := parts.URL()
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if client.credentials.AccessKeyId != "" {
+		region := parts.Region
+		if region == "" {
+			region = s3ProbeRegion
+		}
+		req.Header.Set(client.scheme.headerPrefix+"content-sha256", emptyPayloadHash)
+		signatureV4Signer{
+			scheme:          client.scheme,
+			accessKeyId:     client.credentials.AccessKeyId,
+			secretAccessKey: client.credentials.SecretAccessKey,
+			sessionToken:    client.credentials.SessionToken,
+			region:          region,
+			service:         client.service,
+		}.sign(req, time.Now())
+	}
+	return http.DefaultClient.Do(req.WithContext(ctx))
+}
+
+func isExpectedStatusCode(statusCode int, expectedStatusCodes []int) bool {
+	for _, expectedStatusCode := range expectedStatusCodes {
+		if statusCode == expectedStatusCode {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseS3URL(t *testing.T) {
+	testCases := []struct {
+		url            string
+		expectedBucket string
+		expectedKey    string
+		expectedRegion string
+		expectedErr    bool
+	}{
+		// path style
+		{"https://s3.amazonaws.com/bucket", "bucket", "", "", false},
+		{"https://s3.amazonaws.com/bucket/key", "bucket", "key", "", false},
+		{"https://s3.us-west-2.amazonaws.com/bucket/dir/key", "bucket", "dir/key", "us-west-2", false},
+		{"https://s3-eu-west-1.amazonaws.com/bucket/dir/", "bucket", "dir/", "eu-west-1", false},
+		// virtual hosted style
+		{"https://bucket.s3.amazonaws.com/", "bucket", "", "", false},
+		{"https://bucket.s3.amazonaws.com/dir/key", "bucket", "dir/key", "", false},
+		{"https://bucket.s3.us-west-2.amazonaws.com/key", "bucket", "key", "us-west-2", false},
+		{"https://bucket.s3-ap-southeast-1.amazonaws.com/key", "bucket", "key", "ap-southeast-1", false},
+		{"https://my.dotted.bucket.s3.eu-central-1.amazonaws.com/key", "my.dotted.bucket", "key", "eu-central-1", false},
+		// hosts of AWS which are not the ones of S3
+		{"https://s3express.amazonaws.com/bucket/key", "", "", "", true},
+		{"https://bucket.s3express.amazonaws.com/key", "", "", "", true},
+		{"https://ec2.amazonaws.com/bucket/key", "", "", "", true},
+	}
+	for _, testCase := range testCases {
+		u, _ := url.Parse(testCase.url)
+		if IsS3Url(*u) == testCase.expectedErr {
+			t.Errorf("%s: got IsS3Url %t", testCase.url, !testCase.expectedErr)
+		}
+		parts, err := ParseS3URL(*u)
+		if testCase.expectedErr {
+			if err == nil {
+				t.Errorf("%s: got no error", testCase.url)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: got error %s", testCase.url, err.Error())
+			continue
+		}
+		if parts.Bucket != testCase.expectedBucket || parts.Key != testCase.expectedKey || parts.Region != testCase.expectedRegion {
+			t.Errorf("%s: got bucket %q, key %q and region %q, expected bucket %q, key %q and region %q", testCase.url,
+				parts.Bucket, parts.Key, parts.Region, testCase.expectedBucket, testCase.expectedKey, testCase.expectedRegion)
+		}
+		if parts.Key != "" {
+			if partsUrl := parts.URL(); partsUrl.String() != testCase.url {
+				t.Errorf("%s: got url %s back", testCase.url, partsUrl.String())
+			}
+		}
+	}
+}
+
+func TestS3URLPartsInRegion(t *testing.T) {
+	testCases := []struct {
+		url         string
+		expectedUrl string
+	}{
+		// buckets addressed by path move to the endpoint of their region
+		{"https://s3.amazonaws.com/bucket/key", "https://s3.eu-west-1.amazonaws.com/bucket/key"},
+		{"https://s3.us-east-1.amazonaws.com/bucket/key", "https://s3.eu-west-1.amazonaws.com/bucket/key"},
+		// virtual hosted buckets are reached through the same host from any region
+		{"https://bucket.s3.amazonaws.com/key", "https://bucket.s3.amazonaws.com/key"},
+	}
+	for _, testCase := range testCases {
+		u, _ := url.Parse(testCase.url)
+		parts, _ := ParseS3URL(*u)
+		parts = parts.inRegion("eu-west-1")
+		if partsUrl := parts.URL(); parts.Region != "eu-west-1" || partsUrl.String() != testCase.expectedUrl {
+			t.Errorf("%s: got url %s in region %s, expected url %s", testCase.url, partsUrl.String(), parts.Region, testCase.expectedUrl)
+		}
+	}
+}
+
+// a request signed for another region than the one of the bucket is sent again, signed for the region S3 tells
+func TestS3ClientLooksUpBucketRegion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if !strings.Contains(req.Header.Get("Authorization"), "/eu-west-1/s3/aws4_request") {
+			resp.Header().Set("x-amz-bucket-region", "eu-west-1")
+			resp.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		resp.Header().Set("Content-Length", "5")
+		resp.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	os.Setenv(S3EndpointEnvVar, server.URL)
+	defer os.Unsetenv(S3EndpointEnvVar)
+
+	u, _ := url.Parse(server.URL + "/bucket/key")
+	parts, _ := ParseS3URL(*u)
+	if parts.Region != "" {
+		t.Fatalf("got region %s for a url which does not name it", parts.Region)
+	}
+	client := NewS3Client(S3Credentials{AccessKeyId: "AKIDEXAMPLE", SecretAccessKey: "secret"})
+	properties, err := client.GetObjectProperties(context.Background(), parts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if properties.Size != 5 {
+		t.Errorf("got size %d, expected 5", properties.Size)
+	}
+}
+
+// a bucket whose region is unknown, and which S3 does not tell, fails with an error naming the region as the cause
+func TestS3ClientFailsWithoutBucketRegion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.WriteHeader(http.StatusMovedPermanently)
+	}))
+	defer server.Close()
+	os.Setenv(S3EndpointEnvVar, server.URL)
+	defer os.Unsetenv(S3EndpointEnvVar)
+
+	u, _ := url.Parse(server.URL + "/other-bucket/key")
+	parts, _ := ParseS3URL(*u)
+	client := NewS3Client(S3Credentials{AccessKeyId: "AKIDEXAMPLE", SecretAccessKey: "secret"})
+	_, err := client.GetObjectProperties(context.Background(), parts)
+	if err == nil || !strings.Contains(err.Error(), "region of bucket other-bucket is unknown") {
+		t.Errorf("got error %v, expected the region of the bucket to be unknown", err)
+	}
+}
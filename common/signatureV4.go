@@ -0,0 +1,141 @@
+package common
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is the hex encoded SHA256 of an empty body, which is the payload of the requests signed by azs
+// S3 expects it in the content-sha256 header as well, which the client sets before signing
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// signatureV4Scheme names the algorithm, the key prefix, the request type of the scope and the prefix of the signed headers
+// of a flavor of the Signature Version 4
+type signatureV4Scheme struct {
+	algorithm    string
+	keyPrefix    string
+	requestType  string
+	headerPrefix string
+}
+
+// awsSignatureV4 is the AWS Signature Version 4, as S3 expects it
+var awsSignatureV4 = signatureV4Scheme{"AWS4-HMAC-SHA256", "AWS4", "aws4_request", "x-amz-"}
+
+// googSignatureV4 is the same signature under the names GCS expects for HMAC keys
+var googSignatureV4 = signatureV4Scheme{"GOOG4-HMAC-SHA256", "GOOG4", "goog4_request", "x-goog-"}
+
+// signatureV4Signer signs requests with a flavor of the Signature Version 4
+type signatureV4Signer struct {
+	scheme          signatureV4Scheme
+	accessKeyId     string
+	secretAccessKey string
+	sessionToken    string
+	region          string
+	service         string
+}
+
+// sign adds the date and the authorization headers to a request without body
+// the host and the headers with the prefix of the scheme are signed
+// the path and the query of the request url are sent as they are signed, in their canonical encoding
+func (signer signatureV4Signer) sign(req *http.Request, signingTime time.Time) {
+	signingTime = signingTime.UTC()
+	requestDate := signingTime.Format("20060102T150405Z")
+	shortDate := signingTime.Format("20060102")
+
+	req.URL.RawPath = uriEncode(req.URL.Path, false)
+	req.URL.RawQuery = canonicalQueryString(req.URL.Query())
+	req.Header.Set(signer.scheme.headerPrefix+"date", requestDate)
+	if signer.sessionToken != "" {
+		req.Header.Set(signer.scheme.headerPrefix+"security-token", signer.sessionToken)
+	}
+
+	// step 1: build the canonical request out of the host and the headers with the prefix of the scheme
+	signedHeaderValues := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lowerName := strings.ToLower(name)
+		if strings.HasPrefix(lowerName, signer.scheme.headerPrefix) {
+			signedHeaderValues[lowerName] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	signedHeaderNames := make([]string, 0, len(signedHeaderValues))
+	for name := range signedHeaderValues {
+		signedHeaderNames = append(signedHeaderNames, name)
+	}
+	sort.Strings(signedHeaderNames)
+	canonicalHeaders := ""
+	for _, name := range signedHeaderNames {
+		canonicalHeaders += name + ":" + signedHeaderValues[name] + "\n"
+	}
+	signedHeaders := strings.Join(signedHeaderNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.RawPath,
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+
+	// step 2: build the string to sign for the scope of the date, region and service
+	scope := strings.Join([]string{shortDate, signer.region, signer.service, signer.scheme.requestType}, "/")
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{signer.scheme.algorithm, requestDate, scope, hex.EncodeToString(canonicalRequestHash[:])}, "\n")
+
+	// step 3: derive the signing key and sign
+	signingKey := hmacSHA256([]byte(signer.scheme.keyPrefix+signer.secretAccessKey), shortDate)
+	signingKey = hmacSHA256(signingKey, signer.region)
+	signingKey = hmacSHA256(signingKey, signer.service)
+	signingKey = hmacSHA256(signingKey, signer.scheme.requestType)
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		signer.scheme.algorithm, signer.accessKeyId, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQueryString encodes the query sorted by parameter name, as the signature expects it
+func canonicalQueryString(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var parameters []string
+	for _, name := range names {
+		values := query[name]
+		sort.Strings(values)
+		for _, value := range values {
+			parameters = append(parameters, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(parameters, "&")
+}
+
+// uriEncode percent encodes every byte except the unreserved characters of RFC 3986, and except '/' unless encodeSlash is set
+func uriEncode(s string, encodeSlash bool) string {
+	var encoded bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' || c == '/' && !encodeSlash {
+			encoded.WriteByte(c)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
+	}
+	return encoded.String()
+}
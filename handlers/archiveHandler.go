@@ -0,0 +1,247 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package handlers
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ArchiveTar is the only archive format supported by the archive flag
+const ArchiveTar = "tar"
+
+// HandleUploadArchiveToWastore uploads a local directory, or a single file, as a single tar blob
+// the tree is walked and archived while the archive is uploaded, so the archive is never written to disk
+// the entries of the archive are named relative to the directory, the files are matched against the include/exclude filters
+func HandleUploadArchiveToWastore(commandLineInput *common.CopyCmdArgsAndFlags) {
+	if _, err := os.Stat(commandLineInput.Source); err != nil {
+		panic("cannot access source, not a valid local file system path")
+	}
+
+	// attempt to parse the destination url
+	destinationUrl, err := url.Parse(commandLineInput.Destination)
+	if err != nil {
+		panic(err)
+	}
+
+	// if a container url or a virtual directory is given, the archive is named after the source
+	archiveName := filepath.Base(filepath.Clean(commandLineInput.Source)) + ".tar"
+	headers := azblob.BlobHTTPHeaders{ContentType: "application/x-tar", ContentEncoding: commandLineInput.ContentEncoding}
+	if commandLineInput.Gzip {
+		archiveName += ".gz"
+		headers.ContentType = "application/gzip"
+	}
+	if commandLineInput.ContentType != "" {
+		headers.ContentType = commandLineInput.ContentType
+	}
+	if !strings.Contains(destinationUrl.Path[1:], "/") || strings.HasSuffix(destinationUrl.Path, "/") {
+		destinationUrl.Path = fmt.Sprintf("%s/%s", strings.TrimSuffix(destinationUrl.Path, "/"), archiveName)
+	}
+
+	// the archive is written into a pipe by the walk of the tree, and read out of it block by block by the upload
+	startTime := time.Now()
+	numOfFiles := 0
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		var err error
+		numOfFiles, err = writeTarArchive(commandLineInput.Source, commandLineInput.Gzip,
+			newNameFilter(commandLineInput.Include, commandLineInput.Exclude), pipeWriter)
+		pipeWriter.CloseWithError(err)
+	}()
+	numOfBytes, err := uploadStreamToBlockBlob(pipeReader, *destinationUrl, streamBlockSize(commandLineInput), headers)
+	if err != nil {
+		// the walk stops writing once the pipe is closed
+		pipeReader.CloseWithError(err)
+		panic(fmt.Sprintf("cannot upload the archive of the source: %s", err.Error()))
+	}
+	fmt.Println(fmt.Sprintf("archived %d files into %d bytes in %v", numOfFiles, numOfBytes, time.Since(startTime)))
+}
+
+// HandleExtractArchiveFromWastore downloads a tar blob and extracts it into the local destination directory
+// archives compressed with gzip are recognized by their content, whatever the name of the blob
+func HandleExtractArchiveFromWastore(commandLineInput *common.CopyCmdArgsAndFlags) {
+	// attempt to parse the source url
+	sourceUrl, err := url.Parse(commandLineInput.Source)
+	if err != nil {
+		panic(err)
+	}
+
+	err = os.MkdirAll(commandLineInput.Destination, os.ModePerm)
+	if err != nil {
+		panic(fmt.Sprintf("cannot create the destination directory: %s", err.Error()))
+	}
+
+	// the blob is downloaded into a pipe, and extracted while it is read out of it
+	startTime := time.Now()
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		_, err := downloadBlobToStream(*sourceUrl, streamBlockSize(commandLineInput), pipeWriter)
+		pipeWriter.CloseWithError(err)
+	}()
+	numOfFiles, err := extractTarArchive(pipeReader, commandLineInput.Destination)
+	if err != nil {
+		// the download stops once the pipe is closed
+		pipeReader.CloseWithError(err)
+		panic(fmt.Sprintf("cannot extract the source archive: %s", err.Error()))
+	}
+	fmt.Println(fmt.Sprintf("extracted %d files in %v", numOfFiles, time.Since(startTime)))
+}
+
+// writeTarArchive writes the tar archive of the local directory, or of the single file, to the stream
+// only directories and regular files are archived, and returns the number of files archived
+func writeTarArchive(root string, useGzip bool, filter nameFilter, stream io.Writer) (int, error) {
+	archiveStream := stream
+	var gzipWriter *gzip.Writer
+	if useGzip {
+		gzipWriter = gzip.NewWriter(stream)
+		archiveStream = gzipWriter
+	}
+	tarWriter := tar.NewWriter(archiveStream)
+
+	// a directory is archived with the paths relative to it, a single file under its own name
+	root = filepath.Clean(root)
+	baseDirectory := root
+	if rootInfo, err := os.Stat(root); err == nil && !rootInfo.IsDir() {
+		baseDirectory = filepath.Dir(root)
+	}
+
+	numOfFiles := 0
+	err := filepath.Walk(root, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(baseDirectory, filePath)
+		if err != nil || relativePath == "." {
+			return err
+		}
+		entryName := filepath.ToSlash(relativePath)
+
+		// links and devices are left out, so that the archive only holds what can be extracted safely
+		if fileInfo.IsDir() {
+			entryName += "/"
+		} else if !fileInfo.Mode().IsRegular() || !filter(entryName) {
+			return nil
+		}
+
+		header, err := tar.FileInfoHeader(fileInfo, "")
+		if err != nil {
+			return err
+		}
+		header.Name = entryName
+		err = tarWriter.WriteHeader(header)
+		if err != nil || fileInfo.IsDir() {
+			return err
+		}
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(tarWriter, file)
+		numOfFiles++
+		return err
+	})
+	if err != nil {
+		return numOfFiles, err
+	}
+
+	err = tarWriter.Close()
+	if err == nil && gzipWriter != nil {
+		err = gzipWriter.Close()
+	}
+	return numOfFiles, err
+}
+
+// extractTarArchive extracts the tar archive read from the stream into the destination directory
+// only directories and regular files are extracted, and returns the number of files extracted
+func extractTarArchive(stream io.Reader, destination string) (int, error) {
+	// gzip streams start with the magic number 1f 8b
+	bufferedStream := bufio.NewReader(stream)
+	var archiveStream io.Reader = bufferedStream
+	if magicNumber, err := bufferedStream.Peek(2); err == nil && magicNumber[0] == 0x1f && magicNumber[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(bufferedStream)
+		if err != nil {
+			return 0, err
+		}
+		defer gzipReader.Close()
+		archiveStream = gzipReader
+	}
+	tarReader := tar.NewReader(archiveStream)
+
+	numOfFiles := 0
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return numOfFiles, nil
+		}
+		if err != nil {
+			return numOfFiles, err
+		}
+		entryPath, err := joinUnderDirectory(destination, header.Name)
+		if err != nil {
+			return numOfFiles, err
+		}
+
+		// links and devices are skipped, since they could lead outside of the destination
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(entryPath, os.ModePerm)
+		case tar.TypeReg, tar.TypeRegA:
+			err = extractTarFile(tarReader, header, entryPath)
+			numOfFiles++
+		}
+		if err != nil {
+			return numOfFiles, err
+		}
+	}
+}
+
+// extractTarFile writes the content of the current entry of the archive to the file, creating its directories first
+// the file keeps the permissions and the modification time recorded in the archive
+func extractTarFile(tarReader *tar.Reader, header *tar.Header, filePath string) error {
+	err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, header.FileInfo().Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, tarReader)
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	return os.Chtimes(filePath, header.ModTime, header.ModTime)
+}
@@ -0,0 +1,289 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handlers
+
+import (
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// benchResult represents the outcome of a single benchmark run
+type benchResult struct {
+	direction    string
+	blockSize    uint32
+	numOfWorkers string
+	numOfBytes   int64
+	duration     time.Duration
+	summary      common.JobProgressSummary
+}
+
+// handles the bench command
+// generates synthetic files and pushes them through the storage engine for each combination of block size and worker count
+func HandleBenchCommand(commandLineInput common.BenchCmdArgsAndFlags) {
+	blockSizes := []uint32{commandLineInput.BlockSize}
+	if commandLineInput.SweepBlockSizes != "" {
+		blockSizes = nil
+		for _, value := range parseBenchSweep(commandLineInput.SweepBlockSizes) {
+			blockSizes = append(blockSizes, uint32(value))
+		}
+	}
+	// a worker count of 0 represents the current number of workers of the storage engine
+	workerCounts := []uint64{0}
+	if commandLineInput.SweepWorkers != "" {
+		workerCounts = parseBenchSweep(commandLineInput.SweepWorkers)
+		// the storage engine goes back to the number of workers it had before the sweep once the sweep is done
+		defer setNumOfEngineWorkers(getNumOfEngineWorkers())
+	}
+
+	containerUrl, err := url.Parse(commandLineInput.ContainerUrl)
+	if err != nil {
+		panic(err)
+	}
+
+	// step 1: generate the files to transfer
+	benchDirectory := createBenchDirectory(commandLineInput.InMemory)
+	defer os.RemoveAll(benchDirectory)
+	fmt.Println(fmt.Sprintf("generating %d files of %d bytes in %s", commandLineInput.FileCount, commandLineInput.FileSize, benchDirectory))
+	fileNames := generateBenchFiles(benchDirectory, commandLineInput.FileCount, commandLineInput.FileSize)
+	totalNumOfBytes := int64(commandLineInput.FileCount) * commandLineInput.FileSize
+
+	// step 2: run the benchmark for each combination of worker count and block size
+	var results []benchResult
+	for _, workerCount := range workerCounts {
+		numOfWorkers := "current"
+		if workerCount != 0 {
+			setNumOfEngineWorkers(int(workerCount))
+			numOfWorkers = strconv.FormatUint(workerCount, 10)
+		}
+		for _, blockSize := range blockSizes {
+			// each run uploads to its own virtual directory, so that the runs do not interfere with each other
+			runId, err := newUUID()
+			if err != nil {
+				panic("Failed to generate run id")
+			}
+			cleanContainerPath, prefix := splitContainerPathAndPrefix(containerUrl.Path)
+			var uploadTransfers, downloadTransfers, deleteTransfers []common.CopyTransfer
+			downloadDirectory := filepath.Join(benchDirectory, "download-"+runId)
+			for _, fileName := range fileNames {
+				blobUrl := *containerUrl
+				blobUrl.Path = cleanContainerPath + "/" + prefix + "azs-bench-" + runId + "/" + fileName
+				uploadTransfers = append(uploadTransfers, common.CopyTransfer{
+					Source:           filepath.Join(benchDirectory, fileName),
+					Destination:      blobUrl.String(),
+					LastModifiedTime: time.Now(),
+					SourceSize:       commandLineInput.FileSize,
+				})
+				downloadTransfers = append(downloadTransfers, common.CopyTransfer{
+					Source:           blobUrl.String(),
+					Destination:      filepath.Join(downloadDirectory, fileName),
+					LastModifiedTime: time.Now(),
+					SourceSize:       commandLineInput.FileSize,
+				})
+				deleteTransfers = append(deleteTransfers, common.CopyTransfer{Source: blobUrl.String()})
+			}
+
+			fmt.Println(fmt.Sprintf("running upload with block size %d and %s workers", blockSize, numOfWorkers))
+			result := runBenchJob(common.Local, common.Blob, uploadTransfers, blockSize, commandLineInput.LogVerbosity)
+			result.direction, result.numOfWorkers, result.numOfBytes = "upload", numOfWorkers, totalNumOfBytes
+			results = append(results, result)
+
+			if commandLineInput.Download {
+				err = os.MkdirAll(downloadDirectory, os.ModePerm)
+				if err != nil {
+					panic(err)
+				}
+				fmt.Println(fmt.Sprintf("running download with block size %d and %s workers", blockSize, numOfWorkers))
+				result = runBenchJob(common.Blob, common.Local, downloadTransfers, blockSize, commandLineInput.LogVerbosity)
+				result.direction, result.numOfWorkers, result.numOfBytes = "download", numOfWorkers, totalNumOfBytes
+				results = append(results, result)
+				os.RemoveAll(downloadDirectory)
+			}
+
+			// the blobs uploaded by the run are deleted by a deletion job, which is not measured
+			runBenchJob(common.Blob, common.Delete, deleteTransfers, blockSize, commandLineInput.LogVerbosity)
+		}
+	}
+
+	// step 3: report the results
+	printBenchResults(results)
+}
+
+// parseBenchSweep parses the comma separated list of positive integers given for a sweep
+func parseBenchSweep(sweep string) []uint64 {
+	var values []uint64
+	for _, value := range strings.Split(sweep, ",") {
+		parsedValue, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+		if err != nil || parsedValue == 0 {
+			panic(fmt.Sprintf("invalid value %s in sweep %s, only positive integers are allowed", value, sweep))
+		}
+		values = append(values, parsedValue)
+	}
+	return values
+}
+
+// inMemoryDirectory is the directory backed by memory the files are generated in, on the systems which have one
+const inMemoryDirectory = "/dev/shm"
+
+// createBenchDirectory creates the directory holding the generated files
+// in memory, the files are generated in /dev/shm since the storage engine memory maps the files it uploads
+// other systems than Linux have no such directory, the files are generated in the temp dir there
+func createBenchDirectory(inMemory bool) string {
+	parentDirectory := os.TempDir()
+	if inMemory {
+		if fileInfo, err := os.Stat(inMemoryDirectory); runtime.GOOS == "linux" && err == nil && fileInfo.IsDir() {
+			parentDirectory = inMemoryDirectory
+		} else {
+			fmt.Println(fmt.Sprintf("cannot generate the files in memory since %s is not available on this system, generating them in %s instead", inMemoryDirectory, parentDirectory))
+		}
+	}
+	benchDirectory, err := ioutil.TempDir(parentDirectory, "azs-bench-")
+	if err != nil {
+		panic(err)
+	}
+	return benchDirectory
+}
+
+// generateBenchFiles generates the given number of files of given size filled with random data
+// returns the names of the files generated
+func generateBenchFiles(benchDirectory string, fileCount uint32, fileSize int64) []string {
+	randomGenerator := rand.New(rand.NewSource(time.Now().UnixNano()))
+	buffer := make([]byte, 1024*1024)
+	fileNames := make([]string, fileCount)
+	for index := range fileNames {
+		fileNames[index] = fmt.Sprintf("bench-%d", index)
+		file, err := os.Create(filepath.Join(benchDirectory, fileNames[index]))
+		if err != nil {
+			panic(err)
+		}
+		for remainingBytes := fileSize; remainingBytes > 0; remainingBytes -= int64(len(buffer)) {
+			chunk := buffer
+			if remainingBytes < int64(len(chunk)) {
+				chunk = chunk[:remainingBytes]
+			}
+			randomGenerator.Read(chunk)
+			if _, err = file.Write(chunk); err != nil {
+				panic(err)
+			}
+		}
+		file.Close()
+	}
+	return fileNames
+}
+
+// runBenchJob dispatches a job with the given transfers to the storage engine and waits until it completes
+func runBenchJob(sourceType common.LocationType, destinationType common.LocationType, transfers []common.CopyTransfer, blockSize uint32, logVerbosity uint8) benchResult {
+	jobPartOrder := common.CopyJobPartOrder{}
+	jobPartOrder.OptionalAttributes = common.BlobTransferAttributes{BlockSizeinBytes: blockSize}
+	jobPartOrder.LogVerbosity = common.LogSeverity(logVerbosity)
+	uuid, err := newUUID()
+	if err != nil {
+		panic("Failed to generate job id")
+	}
+	jobPartOrder.ID = common.JobID(uuid)
+
+	// deletions are dispatched like the ones of the remove command, as transfers of the source towards the Delete destination
+	startTime := time.Now()
+	jobPartOrder.SourceType = sourceType
+	jobPartOrder.DestinationType = destinationType
+	dispatcher := newTransferDispatcher(&jobPartOrder, generateCoordinatorScheduleFunc())
+	for _, transfer := range transfers {
+		dispatcher.add(transfer)
+	}
+	dispatcher.dispatchFinalPart()
+	summary := fetchJobProgressSummary(uuid, true)
+	for ; summary.JobStatus != common.StatusCompleted && summary.JobStatus != common.StatusCancelled; summary = fetchJobProgressSummary(uuid, true) {
+		time.Sleep(100 * time.Millisecond)
+	}
+	duration := time.Since(startTime)
+
+	// the job is removed from the storage engine along with its job part plan files, the results are in the summary
+	cleanBenchJob(uuid)
+	return benchResult{blockSize: blockSize, duration: duration, summary: summary}
+}
+
+// cleanBenchJob removes the job of a run from the storage engine, along with its job part plan files and log file
+// the chunks of the job may still be draining right after it completed, so the clean is attempted again for a while
+func cleanBenchJob(jobId string) {
+	extraParams := url.Values{}
+	extraParams.Add("OlderThan", "0")
+	statusCode, message := 0, ""
+	for attempt := 0; attempt < 50; attempt++ {
+		statusCode, message = sendJobControlRequestToSTE("DELETE", "clean", jobId, extraParams, nil)
+		if statusCode == http.StatusAccepted {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	fmt.Println(fmt.Sprintf("failed to clean the bench job %s with status %d : %s", jobId, statusCode, message))
+}
+
+// getNumOfEngineWorkers returns the number of workers the storage engine was last asked to scale to
+func getNumOfEngineWorkers() int {
+	statusCode, message := sendJobControlRequestToSTE("GET", "workers", "", nil, nil)
+	if statusCode != http.StatusAccepted {
+		panic(fmt.Sprintf("failed to get the number of workers of the storage engine with status %d : %s", statusCode, message))
+	}
+	numOfWorkers, err := strconv.Atoi(strings.TrimSpace(message))
+	if err != nil {
+		panic(fmt.Sprintf("invalid number of workers %s returned by the storage engine", message))
+	}
+	return numOfWorkers
+}
+
+// setNumOfEngineWorkers asks the storage engine to scale to the given number of workers
+func setNumOfEngineWorkers(numOfWorkers int) {
+	extraParams := url.Values{}
+	extraParams.Add("Count", strconv.Itoa(numOfWorkers))
+	statusCode, message := sendJobControlRequestToSTE("PUT", "workers", "", extraParams, nil)
+	if statusCode != http.StatusAccepted {
+		panic(fmt.Sprintf("failed to scale the workers of the storage engine with status %d : %s", statusCode, message))
+	}
+}
+
+// printBenchResults prints the results of the benchmark runs as a table
+func printBenchResults(results []benchResult) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "DIRECTION\tBLOCK SIZE\tWORKERS\tSECONDS\tMB/s\tCHUNKS\tP50 ms\tP90 ms\tP99 ms\tFAILED")
+	for _, result := range results {
+		// only the bytes of the completed transfers count towards the throughput
+		numOfBytes := result.numOfBytes
+		if result.summary.TotalNumberOfTransfer != 0 {
+			numOfBytes = numOfBytes * int64(result.summary.TotalNumberofTransferCompleted) / int64(result.summary.TotalNumberOfTransfer)
+		}
+		fmt.Fprintln(writer, fmt.Sprintf("%s\t%d\t%s\t%.2f\t%.2f\t%d\t%.1f\t%.1f\t%.1f\t%d",
+			result.direction, result.blockSize, result.numOfWorkers, result.duration.Seconds(),
+			float64(numOfBytes)/1024/1024/result.duration.Seconds(), result.summary.NumberOfChunksTransferred,
+			result.summary.ChunkLatencyP50InMilliseconds, result.summary.ChunkLatencyP90InMilliseconds,
+			result.summary.ChunkLatencyP99InMilliseconds, result.summary.TotalNumberofFailedTransfer))
+	}
+	writer.Flush()
+}
@@ -24,8 +24,8 @@ import (
 	"github.com/Azure/azure-storage-azcopy/common"
 	"os"
 	"fmt"
-	"io/ioutil"
 	"path"
+	"path/filepath"
 	"net/url"
 	"strings"
 	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
@@ -46,6 +46,22 @@ func HandleCopyCommand(commandLineInput common.CopyCmdArgsAndFlags) string {
 	jobPartOrder := common.CopyJobPartOrder{}
 	ApplyFlags(&commandLineInput, &jobPartOrder)
 
+	// the standard input and output cannot be reached by the storage engine, so streams are transferred by this process
+	// archives are streams as well, into or out of the local tree
+	if commandLineInput.Archive != "" {
+		HandleUploadArchiveToWastore(&commandLineInput)
+		return ""
+	} else if commandLineInput.Extract {
+		HandleExtractArchiveFromWastore(&commandLineInput)
+		return ""
+	} else if commandLineInput.SourceType == common.Pipe {
+		HandleUploadFromStdinToWastore(&commandLineInput)
+		return ""
+	} else if commandLineInput.DestinationType == common.Pipe {
+		HandleDownloadFromWastoreToStdout(&commandLineInput)
+		return ""
+	}
+
 	// generate job id
 	uuid, err := newUUID()
 	if err != nil {
@@ -54,17 +70,36 @@ func HandleCopyCommand(commandLineInput common.CopyCmdArgsAndFlags) string {
 	jobPartOrder.ID = common.JobID(uuid)
 
 	coordinatorScheduleFunc := generateCoordinatorScheduleFunc()
-	if commandLineInput.SourceType == common.Local && commandLineInput.DestinationType == common.Blob {
+	if commandLineInput.ListOfFiles != "" && commandLineInput.SourceType == common.Local {
+		HandleUploadListOfFilesToWastore(&commandLineInput, &jobPartOrder, coordinatorScheduleFunc)
+	} else if commandLineInput.ListOfFiles != "" {
+		HandleDownloadListOfFilesFromWastore(&commandLineInput, &jobPartOrder, coordinatorScheduleFunc)
+	} else if commandLineInput.SourceType == common.Local && commandLineInput.DestinationType == common.Blob {
 		HandleUploadFromLocalToWastore(&commandLineInput, &jobPartOrder, coordinatorScheduleFunc)
 	} else if commandLineInput.SourceType == common.Blob && commandLineInput.DestinationType == common.Local {
 		HandleDownloadFromWastoreToLocal(&commandLineInput, &jobPartOrder, coordinatorScheduleFunc)
+	} else if commandLineInput.SourceType == common.Blob && commandLineInput.DestinationType == common.Blob {
+		HandleCopyFromWastoreToWastore(&commandLineInput, &jobPartOrder, coordinatorScheduleFunc)
+	} else if commandLineInput.SourceType == common.S3 && commandLineInput.DestinationType == common.Blob {
+		HandleCopyFromS3ToWastore(&commandLineInput, &jobPartOrder, coordinatorScheduleFunc)
+	} else if commandLineInput.SourceType == common.GCS && commandLineInput.DestinationType == common.Blob {
+		HandleCopyFromGCSToWastore(&commandLineInput, &jobPartOrder, coordinatorScheduleFunc)
+	} else if commandLineInput.SourceType == common.Http && commandLineInput.DestinationType == common.Blob {
+		if err := HandleCopyFromHttpToWastore(&commandLineInput, &jobPartOrder, coordinatorScheduleFunc); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	} else if commandLineInput.SourceType == common.Local && commandLineInput.DestinationType == common.File {
+		HandleUploadFromLocalToFileShare(&commandLineInput, &jobPartOrder, coordinatorScheduleFunc)
+	} else if commandLineInput.SourceType == common.File && commandLineInput.DestinationType == common.Local {
+		HandleDownloadFromFileShareToLocal(&commandLineInput, &jobPartOrder, coordinatorScheduleFunc)
 	}
 
 	fmt.Println("Job with id", uuid, "has started.")
 	if commandLineInput.IsaBackgroundOp {
 		return uuid
 	}
-	for jobStatus := fetchJobStatus(uuid); jobStatus != common.StatusCompleted; jobStatus = fetchJobStatus(uuid){
+	for jobStatus := fetchJobStatus(uuid); jobStatus != common.StatusCompleted && jobStatus != common.StatusCancelled; jobStatus = fetchJobStatus(uuid){
 		time.Sleep(time.Second)
 	}
 	return uuid
@@ -92,63 +127,36 @@ func HandleUploadFromLocalToWastore(commandLineInput *common.CopyCmdArgsAndFlags
 	}
 
 	// TODO add source id = last modified time
-	// uploading entire directory to Azure Storage
-	// listing needs to be performed
+	// uploading entire directory to Azure Storage, into a container or a virtual directory
+	// the files keep their path relative to the source directory, the sub-directories are looked into only when the recursive flag is set
 	if sourceFileInfo.IsDir() {
-		files, err := ioutil.ReadDir(commandLineInput.Source)
+		cleanContainerPath, prefix := splitContainerPathAndPrefix(destinationUrl.Path)
+		filter := newNameFilter(commandLineInput.Include, commandLineInput.Exclude)
+		dispatcher := newTransferDispatcher(jobPartOrderToFill, dispatchJobPartOrderFunc)
+
+		walkSummary, err := walkLocalFiles(commandLineInput.Source, commandLineInput.Recursive, commandLineInput.FollowSymlinks, filter, func(relativePath string, filePath string, fileInfo os.FileInfo) {
+			destinationUrl.Path = cleanContainerPath + "/" + prefix + relativePath
+			dispatcher.add(common.CopyTransfer{
+				Source:           filePath,
+				Destination:      destinationUrl.String(),
+				LastModifiedTime: fileInfo.ModTime(),
+				SourceSize:       fileInfo.Size(),
+			})
+		})
 
 		// since source was already validated, it would be surprising if file/directory cannot be accessed at this point
 		if err != nil {
 			panic("cannot access source, not a valid local file system path")
 		}
-
-		// make sure this is a container url
-		if strings.Contains(destinationUrl.Path[1:], "/"){
-			panic("destination is not a valid container url")
-		}
-
-		// temporarily save the path of the container
-		cleanContainerPath := destinationUrl.Path
-		var Transfers []common.CopyTransfer
-		numInTransfers := 0
-		partNumber := 0
-
-		for _, f := range files {
-			if !f.IsDir() {
-				destinationUrl.Path = fmt.Sprintf("%s/%s", cleanContainerPath, f.Name())
-				Transfers = append(Transfers, common.CopyTransfer{
-					Source:           path.Join(commandLineInput.Source, f.Name()),
-					Destination:      destinationUrl.String(),
-					LastModifiedTime: f.ModTime(),
-					SourceSize:      f.Size(),
-				})
-				numInTransfers += 1
-
-				if numInTransfers == NumOfFilesPerUploadJobPart {
-					jobPartOrderToFill.Transfers = Transfers //TODO make truth, more defensive, consider channel
-					jobPartOrderToFill.PartNum = common.PartNumber(partNumber)
-					partNumber += 1
-					dispatchJobPartOrderFunc(jobPartOrderToFill)
-					Transfers = []common.CopyTransfer{}
-					numInTransfers = 0
-				}
-			}
-		}
-
-		if numInTransfers != 0 {
-			jobPartOrderToFill.Transfers = Transfers
-		} else {
-			jobPartOrderToFill.Transfers = []common.CopyTransfer{}
-		}
-		jobPartOrderToFill.PartNum = common.PartNumber(partNumber)
-		jobPartOrderToFill.IsFinalPart = true
-		dispatchJobPartOrderFunc(jobPartOrderToFill)
+		walkSummary.reportSkippedSymlinks()
+		dispatcher.countFiltered(walkSummary.numFilteredFiles)
+		dispatcher.dispatchFinalPart()
 
 	} else { // upload single file
 
-		// if a container url is given, must append file name to it
-		if !strings.Contains(destinationUrl.Path[1:], "/") {
-			destinationUrl.Path = fmt.Sprintf("%s/%s", destinationUrl.Path, sourceFileInfo.Name())
+		// if a container url or a virtual directory is given, must append file name to it
+		if !strings.Contains(destinationUrl.Path[1:], "/") || strings.HasSuffix(destinationUrl.Path, "/") {
+			destinationUrl.Path = fmt.Sprintf("%s/%s", strings.TrimSuffix(destinationUrl.Path, "/"), sourceFileInfo.Name())
 		}
 		//fmt.Println("Upload", path.Join(commandLineInput.Source), "to", destinationUrl.String(), "with size", sourceFileInfo.Size())
 		singleTask := common.CopyTransfer{
@@ -177,80 +185,97 @@ func HandleDownloadFromWastoreToLocal(
 	if err != nil {
 		panic(err)
 	}
-	sourcePathParts := strings.Split(sourceUrl.Path[1:], "/")
-
-	destinationFileInfo, err := os.Stat(commandLineInput.Destination)
-	// something is wrong with the destination, handle if it does not exist, else throw
-	if err != nil {
-
-		// create the destination if it does not exist
-		if os.IsNotExist(err) {
-			if len(sourcePathParts) <  2 { // create the directory if the source is a container
-				err = os.MkdirAll(commandLineInput.Destination, os.ModePerm)
-				if err != nil {
-					panic("failed to create the destination on the local file system")
-				}
-			}
-
-			destinationFileInfo, err = os.Stat(commandLineInput.Destination)
-		} else {
-			panic("cannot access destination, not a valid local file system path")
-		}
-	}
+	sourcePathParts := strings.SplitN(sourceUrl.Path[1:], "/", 2)
+	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{})
 
 	// source is a single blob
-	if len(sourcePathParts) > 1 {
-		p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{})
+	if len(sourcePathParts) > 1 && sourcePathParts[1] != "" && !strings.HasSuffix(sourceUrl.Path, "/") {
 		blobUrl := azblob.NewBlobURL(*sourceUrl, p)
 		blobProperties, err := blobUrl.GetPropertiesAndMetadata(context.Background(), azblob.BlobAccessConditions{})
 		if err != nil {
 			panic("Cannot get blob properties")
 		}
 
-		//TODO figure out what to do when destination is dir for a single blob download
-		//unless file info tells us, it is impossible to know whether the destination is a dir
-		//if destinationFileInfo.IsDir() { // destination is dir, therefore the file name needs to be generated
-		//	blobName := sourcePathParts[1]
-		//	commandLineInput.Destination = path.Join(commandLineInput.Destination, blobName)
-		//}
+		// if an existing directory is given, the file is named after the blob
+		// otherwise the destination is the path of the file, whose directories get created by the storage engine
+		destinationPath := commandLineInput.Destination
+		if destinationFileInfo, err := os.Stat(destinationPath); err == nil && destinationFileInfo.IsDir() {
+			destinationPath = filepath.Join(destinationPath, path.Base(sourcePathParts[1]))
+		}
 
 		singleTask := common.CopyTransfer{
-			Source: sourceUrl.String(),
-			Destination: commandLineInput.Destination,
-			LastModifiedTime:blobProperties.LastModified(),
-			SourceSize:blobProperties.ContentLength(),
+			Source:           sourceUrl.String(),
+			Destination:      destinationPath,
+			LastModifiedTime: blobProperties.LastModified(),
+			SourceSize:       blobProperties.ContentLength(),
 		}
 		jobPartOrderToFill.Transfers = []common.CopyTransfer{singleTask}
 		jobPartOrderToFill.IsFinalPart = true
 		jobPartOrderToFill.PartNum = 0
 		dispatchJobPartOrderFunc(jobPartOrderToFill)
-	} else { // source is a container
-		if !destinationFileInfo.IsDir() {
+	} else { // source is a container or a virtual directory
+		// create the destination if it does not exist
+		err = os.MkdirAll(commandLineInput.Destination, os.ModePerm)
+		if err != nil {
+			panic("failed to create the destination on the local file system")
+		}
+		if destinationFileInfo, err := os.Stat(commandLineInput.Destination); err != nil || !destinationFileInfo.IsDir() {
 			panic("destination should be a directory")
 		}
 
-		p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{})
+		// the blobs keep their names relative to the virtual directory, the virtual directories below it become local directories
+		cleanContainerPath, prefix := splitContainerPathAndPrefix(sourceUrl.Path)
+		sourceUrl.Path = cleanContainerPath
 		containerUrl := azblob.NewContainerURL(*sourceUrl, p)
-		// temporarily save the path of the container
-		cleanContainerPath := sourceUrl.Path
-		var Transfers []common.CopyTransfer
+		filter := newNameFilter(commandLineInput.Include, commandLineInput.Exclude)
 		partNumber := 0
 
 		// iterate over the container
 		for marker := (azblob.Marker{}); marker.NotDone(); {
 			// Get a result segment starting with the blob indicated by the current Marker.
-			listBlob, err := containerUrl.ListBlobs(context.Background(), marker, azblob.ListBlobsOptions{})
+			listBlob, err := containerUrl.ListBlobs(context.Background(), marker, azblob.ListBlobsOptions{
+				Details: azblob.BlobListingDetails{Snapshots: commandLineInput.WithSnapshots},
+				Prefix:  prefix})
 			if err != nil {
 				log.Fatal(err)
 			}
 			marker = listBlob.NextMarker
 
 			// Process the blobs returned in this result segment (if the segment is empty, the loop body won't execute)
+			var Transfers []common.CopyTransfer
+			numFilteredBlobs := uint32(0)
 			for _, blobInfo := range listBlob.Blobs.Blob {
-				sourceUrl.Path = cleanContainerPath + "/" + blobInfo.Name
-				Transfers = append(Transfers, common.CopyTransfer{Source: sourceUrl.String(), Destination: path.Join(commandLineInput.Destination, blobInfo.Name), LastModifiedTime:blobInfo.Properties.LastModified, SourceSize:*blobInfo.Properties.ContentLength})
+				relativeName := strings.TrimPrefix(blobInfo.Name, prefix)
+				// blobs named like a directory only mark virtual directories, which the local directories of the other blobs stand for
+				if isDirectoryMarkerBlob(blobInfo.Name) {
+					continue
+				}
+				if !filter(relativeName) {
+					numFilteredBlobs++
+					continue
+				}
+				destinationPath, err := joinUnderDirectory(commandLineInput.Destination, relativeName)
+				if err != nil {
+					fmt.Println("Skipped blob", blobInfo.Name, "since", err.Error())
+					continue
+				}
+				blobUrl := *sourceUrl
+				blobUrl.Path = cleanContainerPath + "/" + blobInfo.Name
+
+				// a snapshot is addressed by its time, and downloaded next to its blob with the time appended to the name
+				if !blobInfo.Snapshot.IsZero() {
+					blobUrl.RawQuery = appendSnapshotQuery(blobUrl.RawQuery, blobInfo.Snapshot)
+					destinationPath += "." + blobInfo.Snapshot.UTC().Format(localSnapshotTimeFormat)
+				}
+				Transfers = append(Transfers, common.CopyTransfer{
+					Source:           blobUrl.String(),
+					Destination:      destinationPath,
+					LastModifiedTime: blobInfo.Properties.LastModified,
+					SourceSize:       *blobInfo.Properties.ContentLength,
+				})
 			}
 			jobPartOrderToFill.Transfers = Transfers
+			jobPartOrderToFill.NumFilteredEntities = numFilteredBlobs
 			jobPartOrderToFill.PartNum = common.PartNumber(partNumber)
 			partNumber += 1
 			if !marker.NotDone() { // if there is no more segment
@@ -264,7 +289,127 @@ func HandleDownloadFromWastoreToLocal(
 	commandLineInput.BlobType = ""
 }
 
+// snapshot times are given in the snapshot query parameter of blob urls in this format
+const blobSnapshotTimeFormat = "2006-01-02T15:04:05.0000000Z"
+
+// snapshot times are appended to the local names of snapshots in this format, without the colons not every file system allows
+const localSnapshotTimeFormat = "2006-01-02T15-04-05.0000000Z"
+
+// appendSnapshotQuery appends the snapshot parameter to the query of a blob url, the SAS in the query is kept as is
+func appendSnapshotQuery(rawQuery string, snapshot time.Time) string {
+	snapshotQuery := "snapshot=" + url.QueryEscape(snapshot.UTC().Format(blobSnapshotTimeFormat))
+	if rawQuery == "" {
+		return snapshotQuery
+	}
+	return rawQuery + "&" + snapshotQuery
+}
+
+// the blobs are copied server side, the storage service moves the data between the containers/accounts
+// source can be a single blob, a container, or a container with a virtual directory prefix
+func HandleCopyFromWastoreToWastore(
+	commandLineInput *common.CopyCmdArgsAndFlags,
+	jobPartOrderToFill *common.CopyJobPartOrder,
+	dispatchJobPartOrderFunc func(jobPartOrder *common.CopyJobPartOrder)) {
+	// set the source and destination type
+	jobPartOrderToFill.SourceType = common.Blob
+	jobPartOrderToFill.DestinationType = common.Blob
+
+	// attempt to parse the source and destination urls
+	sourceUrl, err := url.Parse(commandLineInput.Source)
+	if err != nil {
+		panic(err)
+	}
+	destinationUrl, err := url.Parse(commandLineInput.Destination)
+	if err != nil {
+		panic(err)
+	}
+	sourcePathParts := strings.SplitN(sourceUrl.Path[1:], "/", 2)
+	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{})
+
+	// source is a single blob
+	if len(sourcePathParts) > 1 && sourcePathParts[1] != "" && !strings.HasSuffix(sourceUrl.Path, "/") {
+		blobUrl := azblob.NewBlobURL(*sourceUrl, p)
+		blobProperties, err := blobUrl.GetPropertiesAndMetadata(context.Background(), azblob.BlobAccessConditions{})
+		if err != nil {
+			panic("Cannot get blob properties")
+		}
+
+		// if a container url or a virtual directory is given, must append blob name to it
+		if !strings.Contains(destinationUrl.Path[1:], "/") || strings.HasSuffix(destinationUrl.Path, "/") {
+			destinationUrl.Path = fmt.Sprintf("%s/%s", strings.TrimSuffix(destinationUrl.Path, "/"), path.Base(sourcePathParts[1]))
+		}
+
+		singleTask := common.CopyTransfer{
+			Source:           sourceUrl.String(),
+			Destination:      destinationUrl.String(),
+			LastModifiedTime: blobProperties.LastModified(),
+			SourceSize:       blobProperties.ContentLength(),
+		}
+		jobPartOrderToFill.Transfers = []common.CopyTransfer{singleTask}
+		jobPartOrderToFill.IsFinalPart = true
+		jobPartOrderToFill.PartNum = 0
+		dispatchJobPartOrderFunc(jobPartOrderToFill)
+		return
+	}
+
+	// source is a container or a virtual directory
+	// the blobs keep their names relative to the source prefix under the destination prefix, the blobs inside
+	// virtual sub-directories are copied only when the recursive flag is set
+	cleanSourceContainerPath, sourcePrefix := splitContainerPathAndPrefix(sourceUrl.Path)
+	cleanDestinationContainerPath, destinationPrefix := splitContainerPathAndPrefix(destinationUrl.Path)
+	sourceUrl.Path = cleanSourceContainerPath
+	containerUrl := azblob.NewContainerURL(*sourceUrl, p)
+	filter := newNameFilter(commandLineInput.Include, commandLineInput.Exclude)
+	partNumber := 0
+
+	// iterate over the source container
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		// Get a result segment starting with the blob indicated by the current Marker.
+		listBlob, err := containerUrl.ListBlobs(context.Background(), marker, azblob.ListBlobsOptions{Prefix: sourcePrefix})
+		if err != nil {
+			log.Fatal(err)
+		}
+		marker = listBlob.NextMarker
+
+		// Process the blobs returned in this result segment (if the segment is empty, the loop body won't execute)
+		var Transfers []common.CopyTransfer
+		numFilteredBlobs := uint32(0)
+		for _, blobInfo := range listBlob.Blobs.Blob {
+			relativeName := strings.TrimPrefix(blobInfo.Name, sourcePrefix)
+			if !commandLineInput.Recursive && strings.Contains(relativeName, "/") {
+				continue
+			}
+			if !filter(relativeName) {
+				numFilteredBlobs++
+				continue
+			}
+			sourceUrl.Path = cleanSourceContainerPath + "/" + blobInfo.Name
+			destinationUrl.Path = cleanDestinationContainerPath + "/" + destinationPrefix + relativeName
+			Transfers = append(Transfers, common.CopyTransfer{
+				Source:           sourceUrl.String(),
+				Destination:      destinationUrl.String(),
+				LastModifiedTime: blobInfo.Properties.LastModified,
+				SourceSize:       *blobInfo.Properties.ContentLength,
+			})
+		}
+		jobPartOrderToFill.Transfers = Transfers
+		jobPartOrderToFill.NumFilteredEntities = numFilteredBlobs
+		jobPartOrderToFill.PartNum = common.PartNumber(partNumber)
+		partNumber += 1
+		if !marker.NotDone() { // if there is no more segment
+			jobPartOrderToFill.IsFinalPart = true
+		}
+		dispatchJobPartOrderFunc(jobPartOrderToFill)
+	}
+}
+
 func ApplyFlags(commandLineInput *common.CopyCmdArgsAndFlags, jobPartOrderToFill *common.CopyJobPartOrder)  {
+	// the blob type was validated along with the arguments
+	blobType, err := common.ParseBlobType(commandLineInput.BlobType)
+	if err != nil {
+		panic(err)
+	}
+
 	optionalAttributes := common.BlobTransferAttributes{
 		BlockSizeinBytes: commandLineInput.BlockSize,
 		ContentType: commandLineInput.ContentType,
@@ -272,12 +417,12 @@ func ApplyFlags(commandLineInput *common.CopyCmdArgsAndFlags, jobPartOrderToFill
 		Metadata: commandLineInput.Metadata,
 		NoGuessMimeType: commandLineInput.NoGuessMimeType,
 		PreserveLastModifiedTime: commandLineInput.PreserveLastModifiedTime,
+		BlobType: blobType,
 	}
 
 	jobPartOrderToFill.OptionalAttributes = optionalAttributes
 	jobPartOrderToFill.LogVerbosity = common.LogSeverity(commandLineInput.LogVerbosity)
 	jobPartOrderToFill.IsaBackgroundOp = commandLineInput.IsaBackgroundOp
-	//jobPartOrderToFill.DestinationBlobType = commandLineInput.BlobType
 	//jobPartOrderToFill.Acl = commandLineInput.Acl
 	//jobPartOrderToFill.BlobTier = commandLineInput.BlobTier
 }
@@ -296,3 +441,48 @@ func newUUID() (string, error) {
 	return fmt.Sprintf("%x%x%x%x%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:]), nil
 }
 
+// transferDispatcher batches the transfers of a job into parts of NumOfFilesPerUploadJobPart transfers
+type transferDispatcher struct {
+	jobPartOrder             *common.CopyJobPartOrder
+	dispatchJobPartOrderFunc func(jobPartOrder *common.CopyJobPartOrder)
+	transfers                []common.CopyTransfer
+	numFilteredEntities      uint32
+	partNumber               common.PartNumber
+}
+
+func newTransferDispatcher(jobPartOrder *common.CopyJobPartOrder,
+	dispatchJobPartOrderFunc func(jobPartOrder *common.CopyJobPartOrder)) *transferDispatcher {
+	return &transferDispatcher{jobPartOrder: jobPartOrder, dispatchJobPartOrderFunc: dispatchJobPartOrderFunc}
+}
+
+// add adds a transfer to the current part, which is dispatched once it is full
+func (dispatcher *transferDispatcher) add(transfer common.CopyTransfer) {
+	dispatcher.transfers = append(dispatcher.transfers, transfer)
+	if len(dispatcher.transfers) == NumOfFilesPerUploadJobPart {
+		dispatcher.dispatch(false)
+	}
+}
+
+// countFiltered counts entities left out by the filters into the current part
+func (dispatcher *transferDispatcher) countFiltered(numFilteredEntities uint32) {
+	dispatcher.numFilteredEntities += numFilteredEntities
+}
+
+// dispatchFinalPart dispatches the remaining transfers as the final part, which is empty if there are none
+func (dispatcher *transferDispatcher) dispatchFinalPart() {
+	dispatcher.dispatch(true)
+}
+
+func (dispatcher *transferDispatcher) dispatch(isFinalPart bool) {
+	dispatcher.jobPartOrder.Transfers = dispatcher.transfers
+	if dispatcher.jobPartOrder.Transfers == nil {
+		dispatcher.jobPartOrder.Transfers = []common.CopyTransfer{}
+	}
+	dispatcher.jobPartOrder.NumFilteredEntities = dispatcher.numFilteredEntities
+	dispatcher.jobPartOrder.PartNum = dispatcher.partNumber
+	dispatcher.jobPartOrder.IsFinalPart = isFinalPart
+	dispatcher.dispatchJobPartOrderFunc(dispatcher.jobPartOrder)
+	dispatcher.transfers = nil
+	dispatcher.numFilteredEntities = 0
+	dispatcher.partNumber += 1
+}
@@ -45,19 +45,51 @@ func sendJobPartOrderToSTE(payload []byte) {
 }
 
 
+// fetchJobStatus fetches the progress summary of the job, prints it and returns the status of the job
 func fetchJobStatus(jobId string) (common.Status){
+	summary := fetchJobProgressSummary(jobId, false)
+
+	tm.Clear()
+	tm.MoveCursor(1,1)
+
+	tm.Println("----------------- Progress Summary for JobId", jobId,"------------------")
+	tm.Println("Total Number of Transfers: ", summary.TotalNumberOfTransfer)
+	tm.Println("Total Number of Transfers Completed: ", summary.TotalNumberofTransferCompleted)
+	tm.Println("Total Number of Transfers Failed: ", summary.TotalNumberofFailedTransfer)
+	tm.Println("Total Number of Transfers Cancelled: ", summary.TotalNumberofTransferCancelled)
+	tm.Println("Total Number of Entities Filtered Out: ", summary.TotalNumberOfFilteredEntities)
+	tm.Println("Job order fully received: ", summary.CompleteJobOrdered)
+
+	tm.Println(tm.Background(tm.Color(tm.Bold(fmt.Sprintf("Job Progress: %d %%", summary.PercentageProgress)), tm.WHITE), tm.GREEN))
+	tm.Println(tm.Background(tm.Color(tm.Bold(fmt.Sprintf("Realtime Throughput: %f MB/s", summary.ThroughputInBytesPerSeconds/1024/1024)), tm.WHITE), tm.BLUE))
+
+
+	for index := 0; index < len(summary.FailedTransfers); index++ {
+		message := fmt.Sprintf("transfer-%d	source: %s	destination: %s", index, summary.FailedTransfers[index].Src, summary.FailedTransfers[index].Dst)
+		fmt.Println(message)
+	}
+
+	tm.Flush()
+
+	return summary.JobStatus
+}
+
+// fetchJobProgressSummary fetches the progress summary of the job from the storage engine
+// the percentiles of the latencies of the chunks are only computed when withChunkLatencies is set
+func fetchJobProgressSummary(jobId string, withChunkLatencies bool) (common.JobProgressSummary){
 	url := "http://localhost:1337"
 	client := &http.Client{}
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil{
 		panic(err)
 	}
-	lsCommand := common.ListJobPartsTransfers{JobId:common.JobID(jobId),ExpectedTransferStatus:math.MaxUint8}
+	lsCommand := common.ListJobPartsTransfers{JobId:common.JobID(jobId),ExpectedTransferStatus:math.MaxUint8,WithChunkLatencies:withChunkLatencies}
 	lsCommandMarshalled, err := json.Marshal(lsCommand)
 	if err != nil{
 		panic(err)
 	}
 	q := req.URL.Query()
+	q.Add("Type", "list")
 	q.Add("command", string(lsCommandMarshalled))
 	req.URL.RawQuery = q.Encode()
 
@@ -77,26 +109,5 @@ func fetchJobStatus(jobId string) (common.Status){
 	}
 	var summary common.JobProgressSummary
 	json.Unmarshal(body, &summary)
-
-	tm.Clear()
-	tm.MoveCursor(1,1)
-
-	tm.Println("----------------- Progress Summary for JobId", jobId,"------------------")
-	tm.Println("Total Number of Transfers: ", summary.TotalNumberOfTransfer)
-	tm.Println("Total Number of Transfers Completed: ", summary.TotalNumberofTransferCompleted)
-	tm.Println("Total Number of Transfers Failed: ", summary.TotalNumberofFailedTransfer)
-	tm.Println("Job order fully received: ", summary.CompleteJobOrdered)
-
-	tm.Println(tm.Background(tm.Color(tm.Bold(fmt.Sprintf("Job Progress: %d %%", summary.PercentageProgress)), tm.WHITE), tm.GREEN))
-	tm.Println(tm.Background(tm.Color(tm.Bold(fmt.Sprintf("Realtime Throughput: %f MB/s", summary.ThroughputInBytesPerSeconds/1024/1024)), tm.WHITE), tm.BLUE))
-
-
-	for index := 0; index < len(summary.FailedTransfers); index++ {
-		message := fmt.Sprintf("transfer-%d	source: %s	destination: %s", index, summary.FailedTransfers[index].Src, summary.FailedTransfers[index].Dst)
-		fmt.Println(message)
-	}
-
-	tm.Flush()
-
-	return summary.JobStatus
-}
\ No newline at end of file
+	return summary
+}
@@ -0,0 +1,189 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// HandleUploadFromLocalToFileShare uploads a local file, or the files of a local directory, into an Azure Files share
+// the directories of the files are created in the share by the transfer engine
+func HandleUploadFromLocalToFileShare(commandLineInput *common.CopyCmdArgsAndFlags,
+	jobPartOrderToFill *common.CopyJobPartOrder,
+	dispatchJobPartOrderFunc func(jobPartOrder *common.CopyJobPartOrder)) {
+
+	// set the source and destination type
+	jobPartOrderToFill.SourceType = common.Local
+	jobPartOrderToFill.DestinationType = common.File
+
+	sourceFileInfo, err := os.Stat(commandLineInput.Source)
+
+	// since source was already validated, it would be surprising if file/directory cannot be accessed at this point
+	if err != nil {
+		panic("cannot access source, not a valid local file system path")
+	}
+
+	// attempt to parse the destination url
+	destinationUrl, err := url.Parse(commandLineInput.Destination)
+	if err != nil {
+		panic(err)
+	}
+	_, destinationPathInShare := common.SplitFileUrlPath(*destinationUrl)
+
+	// upload single file
+	if !sourceFileInfo.IsDir() {
+		// if the root of the share or a directory is given, must append file name to it
+		if destinationPathInShare == "" || strings.HasSuffix(destinationUrl.Path, "/") {
+			destinationUrl.Path = fmt.Sprintf("%s/%s", strings.TrimSuffix(destinationUrl.Path, "/"), sourceFileInfo.Name())
+		}
+		jobPartOrderToFill.Transfers = []common.CopyTransfer{{
+			Source:           commandLineInput.Source,
+			Destination:      destinationUrl.String(),
+			LastModifiedTime: sourceFileInfo.ModTime(),
+			SourceSize:       sourceFileInfo.Size(),
+		}}
+		jobPartOrderToFill.PartNum = 0
+		jobPartOrderToFill.IsFinalPart = true
+		dispatchJobPartOrderFunc(jobPartOrderToFill)
+		return
+	}
+
+	// uploading entire directory, the files keep their path relative to the source directory
+	cleanDirectoryPath := strings.TrimSuffix(destinationUrl.Path, "/")
+	filter := newNameFilter(commandLineInput.Include, commandLineInput.Exclude)
+	dispatcher := newTransferDispatcher(jobPartOrderToFill, dispatchJobPartOrderFunc)
+	addFile := func(relativePath string, fileInfo os.FileInfo) {
+		destinationUrl.Path = cleanDirectoryPath + "/" + relativePath
+		dispatcher.add(common.CopyTransfer{
+			Source:           filepath.Join(commandLineInput.Source, filepath.FromSlash(relativePath)),
+			Destination:      destinationUrl.String(),
+			LastModifiedTime: fileInfo.ModTime(),
+			SourceSize:       fileInfo.Size(),
+		})
+	}
+
+	walkSummary, err := walkLocalFiles(commandLineInput.Source, commandLineInput.Recursive, commandLineInput.FollowSymlinks, filter, func(relativePath string, filePath string, fileInfo os.FileInfo) {
+		addFile(relativePath, fileInfo)
+	})
+
+	// since source was already validated, it would be surprising if file/directory cannot be accessed at this point
+	if err != nil {
+		panic("cannot access source, not a valid local file system path")
+	}
+	walkSummary.reportSkippedSymlinks()
+	dispatcher.countFiltered(walkSummary.numFilteredFiles)
+	dispatcher.dispatchFinalPart()
+}
+
+// HandleDownloadFromFileShareToLocal downloads a file, or the files of a directory, from an Azure Files share
+// the sub-directories of the source directory are looked into only when the recursive flag is set
+func HandleDownloadFromFileShareToLocal(commandLineInput *common.CopyCmdArgsAndFlags,
+	jobPartOrderToFill *common.CopyJobPartOrder,
+	dispatchJobPartOrderFunc func(jobPartOrder *common.CopyJobPartOrder)) {
+
+	// set the source and destination type
+	jobPartOrderToFill.SourceType = common.File
+	jobPartOrderToFill.DestinationType = common.Local
+
+	// attempt to parse the source url
+	sourceUrl, err := url.Parse(commandLineInput.Source)
+	if err != nil {
+		panic(err)
+	}
+	_, sourcePathInShare := common.SplitFileUrlPath(*sourceUrl)
+
+	// source is a single file, unless it is the root of the share or a directory, which is not found as a file
+	if sourcePathInShare != "" && !strings.HasSuffix(sourceUrl.Path, "/") {
+		fileSize, lastModified, err := common.NewFileURL(*sourceUrl).GetProperties(context.Background())
+		if err != nil && !common.IsFileServiceResourceNotFound(err) {
+			panic(fmt.Sprintf("cannot get the properties of the source file: %s", err.Error()))
+		}
+		if err == nil {
+			// a file downloaded into an existing directory keeps its name
+			destination := commandLineInput.Destination
+			if destinationFileInfo, err := os.Stat(destination); err == nil && destinationFileInfo.IsDir() {
+				destination = filepath.Join(destination, path.Base(sourcePathInShare))
+			}
+			jobPartOrderToFill.Transfers = []common.CopyTransfer{{
+				Source:           sourceUrl.String(),
+				Destination:      destination,
+				LastModifiedTime: lastModified,
+				SourceSize:       fileSize,
+			}}
+			jobPartOrderToFill.PartNum = 0
+			jobPartOrderToFill.IsFinalPart = true
+			dispatchJobPartOrderFunc(jobPartOrderToFill)
+			return
+		}
+	}
+
+	// source is a directory, create the destination directory if it does not exist
+	err = os.MkdirAll(commandLineInput.Destination, os.ModePerm)
+	if err != nil {
+		panic("failed to create the destination on the local file system")
+	}
+
+	// the directories are listed breadth first, relative to the source directory
+	cleanDirectoryPath := strings.TrimSuffix(sourceUrl.Path, "/")
+	filter := newNameFilter(commandLineInput.Include, commandLineInput.Exclude)
+	dispatcher := newTransferDispatcher(jobPartOrderToFill, dispatchJobPartOrderFunc)
+	for directoriesToList := []string{""}; len(directoriesToList) > 0; directoriesToList = directoriesToList[1:] {
+		relativeDirectoryPath := directoriesToList[0]
+		sourceUrl.Path = cleanDirectoryPath + "/" + relativeDirectoryPath
+		directoryUrl := common.NewDirectoryURL(*sourceUrl)
+
+		for marker, listingDone := "", false; !listingDone; {
+			listResponse, err := directoryUrl.ListFilesAndDirectories(context.Background(), marker)
+			if err != nil {
+				panic(err)
+			}
+			marker = listResponse.NextMarker
+			listingDone = marker == ""
+
+			for _, fileInfo := range listResponse.Files {
+				relativePath := relativeDirectoryPath + fileInfo.Name
+				if !filter(relativePath) {
+					dispatcher.countFiltered(1)
+					continue
+				}
+				sourceUrl.Path = cleanDirectoryPath + "/" + relativePath
+				dispatcher.add(common.CopyTransfer{
+					Source:      sourceUrl.String(),
+					Destination: filepath.Join(commandLineInput.Destination, filepath.FromSlash(relativePath)),
+					SourceSize:  fileInfo.Properties.ContentLength,
+				})
+			}
+			if commandLineInput.Recursive {
+				for _, directoryInfo := range listResponse.Directories {
+					directoriesToList = append(directoriesToList, relativeDirectoryPath+directoryInfo.Name+"/")
+				}
+			}
+		}
+	}
+	dispatcher.dispatchFinalPart()
+}
@@ -0,0 +1,128 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handlers
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// nameFilter decides whether an entity with given name should be part of a job
+type nameFilter func(name string) bool
+
+// newNameFilter builds the nameFilter for given include and exclude patterns
+// each of them holds semicolon separated glob patterns, an entity is included if it matches any include pattern
+// and none of the exclude patterns, an empty include pattern includes everything, an empty exclude pattern excludes nothing
+// see matchNamePattern for how a pattern is matched against the name
+func newNameFilter(include string, exclude string) nameFilter {
+	includePatterns := splitNamePatterns(include)
+	excludePatterns := splitNamePatterns(exclude)
+	return func(name string) bool {
+		if len(includePatterns) > 0 && !matchAnyNamePattern(includePatterns, name) {
+			return false
+		}
+		return !matchAnyNamePattern(excludePatterns, name)
+	}
+}
+
+// ValidateNamePatterns returns an error if any of the semicolon separated glob patterns is malformed
+func ValidateNamePatterns(patterns string) error {
+	for _, pattern := range splitNamePatterns(patterns) {
+		for _, patternSegment := range strings.Split(pattern, "/") {
+			if _, err := path.Match(patternSegment, ""); err != nil {
+				return fmt.Errorf("invalid pattern %s: %s", pattern, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+func splitNamePatterns(patterns string) []string {
+	var splitPatterns []string
+	for _, pattern := range strings.Split(patterns, ";") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			splitPatterns = append(splitPatterns, pattern)
+		}
+	}
+	return splitPatterns
+}
+
+func matchAnyNamePattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchNamePattern(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchNamePattern matches a glob pattern against the name of an entity, which is relative to the source and uses / as separator
+// a pattern without / is matched against the last segment of the name, e.g. *.parquet matches a/b.parquet
+// a pattern with / is matched against the whole name segment by segment, where ** matches any number of segments,
+// e.g. _tmp/** matches everything under _tmp and **/_tmp/** everything under any directory named _tmp
+func matchNamePattern(pattern string, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		matched, err := path.Match(pattern, path.Base(name))
+		return err == nil && matched
+	}
+	return matchNameSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchNameSegments(patternSegments []string, nameSegments []string) bool {
+	for len(patternSegments) > 0 {
+		if patternSegments[0] == "**" {
+			// ** matches any number of segments, including none
+			for numOfSkippedSegments := 0; numOfSkippedSegments <= len(nameSegments); numOfSkippedSegments++ {
+				if matchNameSegments(patternSegments[1:], nameSegments[numOfSkippedSegments:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(nameSegments) == 0 {
+			return false
+		}
+		if matched, err := path.Match(patternSegments[0], nameSegments[0]); err != nil || !matched {
+			return false
+		}
+		patternSegments, nameSegments = patternSegments[1:], nameSegments[1:]
+	}
+	return len(nameSegments) == 0
+}
+
+// isDirectoryMarkerBlob returns whether the blob only marks a virtual directory, which tools creating directories in containers do
+// with an empty blob named after the directory followed by a /
+func isDirectoryMarkerBlob(blobName string) bool {
+	return strings.HasSuffix(blobName, "/")
+}
+
+// joinUnderDirectory returns the local path of an entity named relative to the directory, such as a blob or an archive entry
+// names with an absolute path, or whose path leads out of the directory, are refused
+func joinUnderDirectory(directory string, relativeName string) (string, error) {
+	cleanName := filepath.Clean(filepath.FromSlash(relativeName))
+	if filepath.IsAbs(cleanName) || filepath.VolumeName(cleanName) != "" ||
+		cleanName == ".." || strings.HasPrefix(cleanName, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("the path %s lies outside of %s", relativeName, directory)
+	}
+	return filepath.Join(directory, cleanName), nil
+}
@@ -0,0 +1,100 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handlers
+
+import (
+	"testing"
+)
+
+func TestNameFilter(t *testing.T) {
+	testCases := []struct {
+		include  string
+		exclude  string
+		name     string
+		expected bool
+	}{
+		// a pattern without / is matched against the last segment of the name
+		{"*.parquet", "", "b.parquet", true},
+		{"*.parquet", "", "a/b.parquet", true},
+		{"*.parquet", "", "a/b.parquet.tmp", false},
+		{"*.parquet", "", "a.parquet/b", false},
+
+		// ** matches any number of segments, including none
+		{"", "_tmp/**", "_tmp/a", false},
+		{"", "_tmp/**", "_tmp/a/b", false},
+		{"", "_tmp/**", "_tmp", false},
+		{"", "_tmp/**", "a/_tmp/b", true},
+		{"", "_tmp/**", "_tmpx/a", true},
+		{"a/**/b", "", "a/b", true},
+		{"a/**/b", "", "a/x/b", true},
+		{"a/**/b", "", "a/x/y/b", true},
+		{"a/**/b", "", "a/b/c", false},
+		{"a/**/b", "", "x/a/b", false},
+
+		// ** alone has no /, so it matches the last segment of any name
+		{"**", "", "a", true},
+		{"**", "", "a/b/c", true},
+		{"", "**", "a/b/c", false},
+
+		// empty patterns left by a trailing ; are ignored
+		{"*.parquet;", "", "a/b.parquet", true},
+		{"*.parquet;", "", "a/b.csv", false},
+		{"", "*.tmp;", "a/b.tmp", false},
+		{"", "*.tmp;", "a/b.csv", true},
+		{";", ";", "a/b.csv", true},
+
+		// an empty include pattern includes everything, an empty exclude pattern excludes nothing
+		{"", "", "a", true},
+		{"", "", "a/b/c", true},
+
+		// an entity must match an include pattern and no exclude pattern
+		{"*.parquet", "_tmp/**", "_tmp/b.parquet", false},
+		{"*.parquet;*.csv", "_tmp/**", "a/b.csv", true},
+	}
+	for _, testCase := range testCases {
+		if included := newNameFilter(testCase.include, testCase.exclude)(testCase.name); included != testCase.expected {
+			t.Errorf("include %q and exclude %q: got %t for %s, expected %t",
+				testCase.include, testCase.exclude, included, testCase.name, testCase.expected)
+		}
+	}
+}
+
+func TestMatchNameSegments(t *testing.T) {
+	testCases := []struct {
+		pattern  []string
+		name     []string
+		expected bool
+	}{
+		{[]string{"**"}, []string{}, true},
+		{[]string{"**"}, []string{"a", "b"}, true},
+		{[]string{"**", "b"}, []string{"b"}, true},
+		{[]string{"**", "b"}, []string{"a", "b", "c"}, false},
+		{[]string{"a", "*"}, []string{"a"}, false},
+		{[]string{"a", "*"}, []string{"a", "b"}, true},
+		{[]string{"a", "**", "**", "b"}, []string{"a", "x", "b"}, true},
+		{[]string{"[a-"}, []string{"a"}, false},
+	}
+	for _, testCase := range testCases {
+		if matched := matchNameSegments(testCase.pattern, testCase.name); matched != testCase.expected {
+			t.Errorf("got %t for pattern %v and name %v, expected %t", matched, testCase.pattern, testCase.name, testCase.expected)
+		}
+	}
+}
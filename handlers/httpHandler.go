@@ -0,0 +1,78 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// HandleCopyFromHttpToWastore uploads the file served at a plain http(s) url into Azure Storage as a block blob
+// the size and the last modified time of the file are taken from a HEAD request, the server must tell the size
+// an error is returned, and nothing is dispatched, if the source cannot be uploaded
+func HandleCopyFromHttpToWastore(commandLineInput *common.CopyCmdArgsAndFlags,
+	jobPartOrderToFill *common.CopyJobPartOrder,
+	dispatchJobPartOrderFunc func(jobPartOrder *common.CopyJobPartOrder)) error {
+	jobPartOrderToFill.SourceType = common.Http
+	jobPartOrderToFill.DestinationType = common.Blob
+
+	// attempt to parse the source and destination urls
+	sourceUrl, err := url.Parse(commandLineInput.Source)
+	if err != nil {
+		return err
+	}
+	destinationUrl, err := url.Parse(commandLineInput.Destination)
+	if err != nil {
+		return err
+	}
+
+	sourceProperties, err := common.GetHttpSourceProperties(context.Background(), *sourceUrl)
+	if err != nil {
+		return fmt.Errorf("cannot get the properties of the source: %s", err.Error())
+	}
+	if sourceProperties.Size < 0 {
+		return errors.New("cannot upload the source since the server does not tell its size")
+	}
+
+	// if a container url or a virtual directory is given, must append the name of the file to it
+	if !strings.Contains(destinationUrl.Path[1:], "/") || strings.HasSuffix(destinationUrl.Path, "/") {
+		fileName := path.Base(sourceUrl.Path)
+		if fileName == "/" || fileName == "." {
+			return errors.New("cannot name the destination blob after the source url, please give the full destination blob url")
+		}
+		destinationUrl.Path = fmt.Sprintf("%s/%s", strings.TrimSuffix(destinationUrl.Path, "/"), fileName)
+	}
+
+	jobPartOrderToFill.Transfers = []common.CopyTransfer{{
+		Source:           sourceUrl.String(),
+		Destination:      destinationUrl.String(),
+		LastModifiedTime: sourceProperties.LastModified,
+		SourceSize:       sourceProperties.Size,
+	}}
+	jobPartOrderToFill.PartNum = 0
+	jobPartOrderToFill.IsFinalPart = true
+	dispatchJobPartOrderFunc(jobPartOrderToFill)
+	return nil
+}
@@ -0,0 +1,148 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// handles the resume command
+// asks the storage engine to reschedule the incomplete transfers of the job
+// the credentials of S3 and GCS sources are read from the environment again, since the storage engine does not persist them
+func HandleResumeCommand(commandLineInput common.ResumeCmdArgsAndFlags) {
+	resumeOrder := common.ResumeJobOrder{
+		S3Credentials:  common.GetS3CredentialsFromEnvironment(),
+		GCSCredentials: common.GetGCSCredentialsFromEnvironment(),
+	}
+	statusCode, message := sendJobControlRequestToSTE("PUT", "resume", commandLineInput.JobId, nil, resumeOrder)
+	if statusCode != http.StatusAccepted {
+		fmt.Println("request failed with status", statusCode, ":", message)
+		os.Exit(1)
+	}
+	fmt.Println(message)
+
+	if commandLineInput.IsaBackgroundOp {
+		return
+	}
+	for jobStatus := fetchJobStatus(commandLineInput.JobId); jobStatus != common.StatusCompleted && jobStatus != common.StatusCancelled; jobStatus = fetchJobStatus(commandLineInput.JobId) {
+		time.Sleep(time.Second)
+	}
+}
+
+// handles the cancel command
+// asks the storage engine to cancel the transfers of the job which are not complete yet
+func HandleCancelCommand(commandLineInput common.CancelCmdArgsAndFlags) {
+	statusCode, message := sendJobControlRequestToSTE("DELETE", "cancel", commandLineInput.JobId, nil, nil)
+	if statusCode != http.StatusAccepted {
+		fmt.Println("request failed with status", statusCode, ":", message)
+		os.Exit(1)
+	}
+	fmt.Println(message)
+}
+
+// handles the pause command
+// asks the storage engine to stop handing the transfers of the job to its workers
+func HandlePauseCommand(commandLineInput common.PauseCmdArgsAndFlags) {
+	statusCode, message := sendJobControlRequestToSTE("PUT", "pause", commandLineInput.JobId, nil, nil)
+	if statusCode != http.StatusAccepted {
+		fmt.Println("request failed with status", statusCode, ":", message)
+		os.Exit(1)
+	}
+	fmt.Println(message)
+}
+
+// handles the unpause command
+// asks the storage engine to continue the transfers of the job from where they were paused
+func HandleUnpauseCommand(commandLineInput common.PauseCmdArgsAndFlags) {
+	statusCode, message := sendJobControlRequestToSTE("PUT", "unpause", commandLineInput.JobId, nil, nil)
+	if statusCode != http.StatusAccepted {
+		fmt.Println("request failed with status", statusCode, ":", message)
+		os.Exit(1)
+	}
+	fmt.Println(message)
+}
+
+// handles the jobs clean command
+// asks the storage engine to remove the completed jobs along with their plan files and log files
+func HandleJobsCleanCommand(commandLineInput common.JobsCleanCmdArgsAndFlags) {
+	// OlderThan is sent to the storage engine in seconds, the storage engine applies the time to live of the jobs without it
+	extraParams := url.Values{}
+	if commandLineInput.OlderThan != nil {
+		extraParams.Add("OlderThan", fmt.Sprintf("%d", int64(*commandLineInput.OlderThan/time.Second)))
+	}
+
+	statusCode, message := sendJobControlRequestToSTE("DELETE", "clean", commandLineInput.JobId, extraParams, nil)
+	if statusCode != http.StatusAccepted {
+		fmt.Println("request failed with status", statusCode, ":", message)
+		os.Exit(1)
+	}
+	fmt.Println(message)
+}
+
+// sendJobControlRequestToSTE sends a request of given method and type for an existing job to the storage engine
+// extraParams are the additional query parameters of the request, if any, and payload is sent as the json body of the request, if any
+// returns the status code and the message of the response
+func sendJobControlRequestToSTE(method string, requestType string, jobId string, extraParams url.Values, payload interface{}) (int, string) {
+	steUrl := "http://localhost:1337"
+	client := &http.Client{}
+	var requestBody io.Reader
+	if payload != nil {
+		payloadData, err := json.Marshal(payload)
+		if err != nil {
+			panic(err)
+		}
+		requestBody = bytes.NewReader(payloadData)
+	}
+	req, err := http.NewRequest(method, steUrl, requestBody)
+	if err != nil {
+		panic(err)
+	}
+	q := req.URL.Query()
+	// Type defines the type of request processed by the transfer engine
+	q.Add("Type", requestType)
+	q.Add("JobId", jobId)
+	for key, values := range extraParams {
+		for _, value := range values {
+			q.Add(key, value)
+		}
+	}
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		panic(err)
+	}
+	return resp.StatusCode, string(body)
+}
@@ -123,6 +123,8 @@ func PrintJobProgressSummary(summaryData []byte, jobId string) (status common.St
 	fmt.Println("Total Number of Transfer ", summary.TotalNumberOfTransfer)
 	fmt.Println("Total Number of Transfer Completed ", summary.TotalNumberofTransferCompleted)
 	fmt.Println("Total Number of Transfer Failed ", summary.TotalNumberofFailedTransfer)
+	fmt.Println("Total Number of Transfer Cancelled ", summary.TotalNumberofTransferCancelled)
+	fmt.Println("Total Number of Entities Filtered Out ", summary.TotalNumberOfFilteredEntities)
 	fmt.Println("Has the final part been ordered ", summary.CompleteJobOrdered)
 	fmt.Println("Progress of Job in terms of Perecentage ", summary.PercentageProgress)
 	for index := 0; index < len(summary.FailedTransfers); index++ {
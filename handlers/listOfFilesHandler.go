@@ -0,0 +1,241 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// the longest line a list of files may hold, i.e. the longest path it names
+const maxListOfFilesLineSize = 64 * 1024
+
+// the number of blobs named in a list of files whose properties are fetched at once
+const numOfListedBlobPropertiesWorkers = 16
+
+// HandleUploadListOfFilesToWastore uploads the files named in the list of files, relative to the source directory
+// the files keep their relative path under the destination container or virtual directory, no other file is looked at
+// the list is read and each file is looked at once, before the first part is dispatched, so that a bad entry does not leave a partial job behind
+func HandleUploadListOfFilesToWastore(commandLineInput *common.CopyCmdArgsAndFlags,
+	jobPartOrderToFill *common.CopyJobPartOrder,
+	dispatchJobPartOrderFunc func(jobPartOrder *common.CopyJobPartOrder)) {
+
+	// set the source and destination type
+	jobPartOrderToFill.SourceType = common.Local
+	jobPartOrderToFill.DestinationType = common.Blob
+
+	if sourceFileInfo, err := os.Stat(commandLineInput.Source); err != nil || !sourceFileInfo.IsDir() {
+		panic("the source should be a local directory when a list of files is given")
+	}
+
+	// attempt to parse the destination url
+	destinationUrl, err := url.Parse(commandLineInput.Destination)
+	if err != nil {
+		panic(err)
+	}
+	cleanContainerPath, prefix := splitContainerPathAndPrefix(destinationUrl.Path)
+
+	// step 1: read the list, the files must exist in the source directory and be regular files, filtered out or not
+	filter := newNameFilter(commandLineInput.Include, commandLineInput.Exclude)
+	var transfers []common.CopyTransfer
+	numFilteredFiles := uint32(0)
+	err = forEachListedFile(commandLineInput.ListOfFiles, func(relativeName string) error {
+		filePath, err := joinUnderDirectory(commandLineInput.Source, relativeName)
+		if err != nil {
+			return err
+		}
+		fileInfo, err := os.Stat(filePath)
+		if err != nil {
+			return err
+		}
+		if !fileInfo.Mode().IsRegular() {
+			return fmt.Errorf("%s is not a regular file", relativeName)
+		}
+		if !filter(relativeName) {
+			numFilteredFiles++
+			return nil
+		}
+		destinationUrl.Path = cleanContainerPath + "/" + prefix + relativeName
+		transfers = append(transfers, common.CopyTransfer{
+			Source:           filePath,
+			Destination:      destinationUrl.String(),
+			LastModifiedTime: fileInfo.ModTime(),
+			SourceSize:       fileInfo.Size(),
+		})
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// step 2: dispatch the files
+	dispatcher := newTransferDispatcher(jobPartOrderToFill, dispatchJobPartOrderFunc)
+	dispatcher.countFiltered(numFilteredFiles)
+	for _, transfer := range transfers {
+		dispatcher.add(transfer)
+	}
+	dispatcher.dispatchFinalPart()
+}
+
+// HandleDownloadListOfFilesFromWastore downloads the blobs named in the list of files, relative to the source container or virtual directory
+// the blobs keep their relative name under the destination directory, the container is not listed
+// the properties of every blob are fetched before the first part is dispatched, so that a bad entry does not leave a partial job behind
+func HandleDownloadListOfFilesFromWastore(commandLineInput *common.CopyCmdArgsAndFlags,
+	jobPartOrderToFill *common.CopyJobPartOrder,
+	dispatchJobPartOrderFunc func(jobPartOrder *common.CopyJobPartOrder)) {
+
+	// set the source and destination type
+	jobPartOrderToFill.SourceType = common.Blob
+	jobPartOrderToFill.DestinationType = common.Local
+
+	// attempt to parse the source url
+	sourceUrl, err := url.Parse(commandLineInput.Source)
+	if err != nil {
+		panic(err)
+	}
+	cleanContainerPath, prefix := splitContainerPathAndPrefix(sourceUrl.Path)
+
+	// step 1: read the list, the blobs must be named relative to the destination, since they are downloaded below it
+	filter := newNameFilter(commandLineInput.Include, commandLineInput.Exclude)
+	var transfers []common.CopyTransfer
+	numFilteredBlobs := uint32(0)
+	err = forEachListedFile(commandLineInput.ListOfFiles, func(relativeName string) error {
+		destinationPath, err := joinUnderDirectory(commandLineInput.Destination, relativeName)
+		if err != nil {
+			return err
+		}
+		if !filter(relativeName) {
+			numFilteredBlobs++
+			return nil
+		}
+		sourceUrl.Path = cleanContainerPath + "/" + prefix + relativeName
+		transfers = append(transfers, common.CopyTransfer{
+			Source:      sourceUrl.String(),
+			Destination: destinationPath,
+		})
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// step 2: fetch the properties of the blobs, a few at a time
+	err = fetchListedBlobProperties(transfers)
+	if err != nil {
+		panic(err)
+	}
+
+	// create the destination if it does not exist
+	err = os.MkdirAll(commandLineInput.Destination, os.ModePerm)
+	if err != nil {
+		panic("failed to create the destination on the local file system")
+	}
+
+	// step 3: dispatch the blobs
+	dispatcher := newTransferDispatcher(jobPartOrderToFill, dispatchJobPartOrderFunc)
+	dispatcher.countFiltered(numFilteredBlobs)
+	for _, transfer := range transfers {
+		dispatcher.add(transfer)
+	}
+	dispatcher.dispatchFinalPart()
+}
+
+// fetchListedBlobProperties fills in the size and last modified time of the source blob of each transfer
+// up to numOfListedBlobPropertiesWorkers blobs are fetched at once, and the first failure cancels the others
+func fetchListedBlobProperties(transfers []common.CopyTransfer) error {
+	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var fetchErr error
+	var fetchErrOnce sync.Once
+	var fetchesDone sync.WaitGroup
+	workerSlots := make(chan struct{}, numOfListedBlobPropertiesWorkers)
+	for index := 0; index < len(transfers) && ctx.Err() == nil; index++ {
+		workerSlots <- struct{}{}
+		fetchesDone.Add(1)
+		go func(transfer *common.CopyTransfer) {
+			defer fetchesDone.Done()
+			defer func() { <-workerSlots }()
+			blobUrl, _ := url.Parse(transfer.Source)
+			blobProperties, err := azblob.NewBlobURL(*blobUrl, p).GetPropertiesAndMetadata(ctx, azblob.BlobAccessConditions{})
+			if err != nil {
+				fetchErrOnce.Do(func() {
+					fetchErr = fmt.Errorf("cannot get the properties of blob %s: %s", transfer.Source, err.Error())
+					cancel()
+				})
+				return
+			}
+			transfer.LastModifiedTime = blobProperties.LastModified()
+			transfer.SourceSize = blobProperties.ContentLength()
+		}(&transfers[index])
+	}
+	fetchesDone.Wait()
+	return fetchErr
+}
+
+// forEachListedFile calls addEntry for each entry of the list of files, which holds a relative path or blob name per line
+// the list is read line by line rather than at once, and blank lines are skipped
+// each entry is given to addEntry normalized, so that uploads and downloads name the same entry alike
+// the first error stops the reading, and is returned along with the line it was found at
+func forEachListedFile(listOfFiles string, addEntry func(relativeName string) error) error {
+	file, err := os.Open(listOfFiles)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 4096), maxListOfFilesLineSize)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		// lists written on Windows end their lines with a carriage return
+		entry := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		relativeName, err := normalizeListedEntry(entry)
+		if err == nil {
+			err = addEntry(relativeName)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid entry at line %d of %s: %s", lineNumber, listOfFiles, err.Error())
+		}
+	}
+	return scanner.Err()
+}
+
+// normalizeListedEntry returns the relative name an entry of the list of files is transferred under
+// the name uses forward slashes, without redundant separators or dot elements, and must lie below the directory it is relative to
+func normalizeListedEntry(entry string) (string, error) {
+	relativeName := path.Clean(filepath.ToSlash(entry))
+	if relativeName == "." || path.IsAbs(relativeName) || relativeName == ".." || strings.HasPrefix(relativeName, "../") {
+		return "", fmt.Errorf("%s does not name a file below the directory it is relative to", entry)
+	}
+	return relativeName, nil
+}
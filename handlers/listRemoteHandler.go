@@ -0,0 +1,171 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// remoteEntity represents a blob, or a virtual directory, printed by the ls-remote command
+type remoteEntity struct {
+	Name         string
+	IsDirectory  bool
+	Size         int64
+	LastModified time.Time
+	BlobType     string
+}
+
+// handles the ls-remote command
+// pages through the blobs of the container and prints the ones matching the filters
+func HandleListRemoteCommand(commandLineInput common.ListRemoteCmdArgsAndFlags) {
+	// attempt to parse the container url
+	sourceUrl, err := url.Parse(commandLineInput.Source)
+	if err != nil {
+		panic(err)
+	}
+	sourcePathParts := strings.SplitN(sourceUrl.Path[1:], "/", 2)
+	prefix := ""
+	if len(sourcePathParts) > 1 {
+		prefix = sourcePathParts[1]
+	}
+	sourceUrl.Path = "/" + sourcePathParts[0]
+
+	// without recursive, the delimiter folds the blobs of the virtual sub-directories into their directory
+	listOptions := azblob.ListBlobsOptions{Prefix: prefix}
+	if !commandLineInput.Recursive {
+		listOptions.Delimiter = "/"
+	}
+
+	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{})
+	containerUrl := azblob.NewContainerURL(*sourceUrl, p)
+	filter := newNameFilter(commandLineInput.Include, commandLineInput.Exclude)
+	printer := newRemoteEntityPrinter(commandLineInput.Output)
+
+	// iterate over the container
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		// Get a result segment starting with the blob indicated by the current Marker.
+		listBlob, err := containerUrl.ListBlobs(context.Background(), marker, listOptions)
+		if err != nil {
+			log.Fatal(err)
+		}
+		marker = listBlob.NextMarker
+
+		// virtual directories are filtered by their name without the trailing delimiter
+		for _, blobPrefix := range listBlob.Blobs.BlobPrefix {
+			if !filter(strings.TrimSuffix(strings.TrimPrefix(blobPrefix.Name, prefix), "/")) {
+				printer.countFiltered()
+				continue
+			}
+			printer.print(remoteEntity{Name: blobPrefix.Name, IsDirectory: true})
+		}
+		for _, blobInfo := range listBlob.Blobs.Blob {
+			if !filter(strings.TrimPrefix(blobInfo.Name, prefix)) {
+				printer.countFiltered()
+				continue
+			}
+			var size int64 = 0
+			if blobInfo.Properties.ContentLength != nil {
+				size = *blobInfo.Properties.ContentLength
+			}
+			printer.print(remoteEntity{
+				Name:         blobInfo.Name,
+				Size:         size,
+				LastModified: blobInfo.Properties.LastModified,
+				BlobType:     string(blobInfo.Properties.BlobType),
+			})
+		}
+		printer.flush()
+	}
+	printer.close()
+}
+
+// remoteEntityPrinter prints the listed entities either as a table or as a JSON array
+// entities are printed segment by segment, so that large containers do not have to be held in memory
+type remoteEntityPrinter struct {
+	outputJson            bool
+	numOfEntities         int
+	numOfBytes            int64
+	numOfFilteredEntities int
+	tableWriter           *tabwriter.Writer
+}
+
+func newRemoteEntityPrinter(output string) *remoteEntityPrinter {
+	printer := &remoteEntityPrinter{outputJson: output == "json"}
+	if printer.outputJson {
+		fmt.Print("[")
+		return printer
+	}
+	printer.tableWriter = tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(printer.tableWriter, "NAME\tSIZE\tLAST MODIFIED\tBLOB TYPE")
+	return printer
+}
+
+func (printer *remoteEntityPrinter) print(entity remoteEntity) {
+	printer.numOfEntities++
+	printer.numOfBytes += entity.Size
+	if printer.outputJson {
+		entityJson, err := json.Marshal(entity)
+		if err != nil {
+			panic(err)
+		}
+		if printer.numOfEntities > 1 {
+			fmt.Print(",")
+		}
+		fmt.Print("\n  " + string(entityJson))
+		return
+	}
+	if entity.IsDirectory {
+		fmt.Fprintln(printer.tableWriter, fmt.Sprintf("%s\t-\t-\tDIR", entity.Name))
+		return
+	}
+	fmt.Fprintln(printer.tableWriter, fmt.Sprintf("%s\t%d\t%s\t%s", entity.Name, entity.Size,
+		entity.LastModified.Format(time.RFC3339), entity.BlobType))
+}
+
+// countFiltered counts an entity left out by the filters
+func (printer *remoteEntityPrinter) countFiltered() {
+	printer.numOfFilteredEntities++
+}
+
+func (printer *remoteEntityPrinter) flush() {
+	if !printer.outputJson {
+		printer.tableWriter.Flush()
+	}
+}
+
+func (printer *remoteEntityPrinter) close() {
+	if printer.outputJson {
+		fmt.Println("\n]")
+		return
+	}
+	printer.tableWriter.Flush()
+	fmt.Println(fmt.Sprintf("%d entries, %d bytes in total, %d entries filtered out", printer.numOfEntities, printer.numOfBytes, printer.numOfFilteredEntities))
+}
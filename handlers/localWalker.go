@@ -0,0 +1,146 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package handlers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// fileIdentity identifies a file regardless of the path it is reached through, by the device and the inode holding it
+type fileIdentity struct {
+	device uint64
+	inode  uint64
+}
+
+// localWalkSummary describes the files a walk of a local directory left out
+type localWalkSummary struct {
+	numFilteredFiles uint32   // the number of files rejected by the filter
+	skippedSymlinks  []string // the symbolic links which were not followed, along with the reason
+}
+
+// reportSkippedSymlinks tells the user about the symbolic links which were left out of the walk
+func (summary localWalkSummary) reportSkippedSymlinks() {
+	for _, skippedSymlink := range summary.skippedSymlinks {
+		fmt.Println("Skipped symbolic link", skippedSymlink)
+	}
+}
+
+// localWalker holds the state of a walk of a local directory
+type localWalker struct {
+	recursive      bool
+	followSymlinks bool
+	filter         nameFilter
+	addFile        func(relativePath string, filePath string, fileInfo os.FileInfo)
+
+	// the directories on the path currently walked, a symbolic link leading to one of them would make the walk endless
+	ancestors map[fileIdentity]bool
+	summary   localWalkSummary
+}
+
+// walkLocalFiles calls addFile for each regular file in the local directory which passes the filter, in lexical order
+// the path of the file relative to the directory uses / as separator, so that it can be used as the name of a blob
+// the sub-directories are looked into only when recursive is set, and other special files are skipped
+// symbolic links are resolved only when followSymlinks is set, a file reached through a link keeps the path of the link
+// links which are not followed, whose target cannot be accessed or which lead into a cycle are skipped and listed in the summary
+// a nil filter passes every file
+func walkLocalFiles(directory string, recursive bool, followSymlinks bool, filter nameFilter,
+	addFile func(relativePath string, filePath string, fileInfo os.FileInfo)) (localWalkSummary, error) {
+	directoryInfo, err := os.Stat(directory)
+	if err != nil {
+		return localWalkSummary{}, err
+	}
+	identity, err := getFileIdentity(directory, directoryInfo)
+	if err != nil {
+		return localWalkSummary{}, err
+	}
+
+	walker := &localWalker{
+		recursive:      recursive,
+		followSymlinks: followSymlinks,
+		filter:         filter,
+		addFile:        addFile,
+		ancestors:      map[fileIdentity]bool{},
+	}
+	err = walker.walkDirectory(directory, "", identity)
+	return walker.summary, err
+}
+
+// walkDirectory walks the entries of a directory, whose path relative to the walked one is relativeDirectory
+func (walker *localWalker) walkDirectory(directoryPath string, relativeDirectory string, identity fileIdentity) error {
+	walker.ancestors[identity] = true
+	defer delete(walker.ancestors, identity)
+
+	// the entries are described by Lstat, so that symbolic links can be told apart
+	entries, err := ioutil.ReadDir(directoryPath)
+	if err != nil {
+		return err
+	}
+	for _, entryInfo := range entries {
+		entryPath := filepath.Join(directoryPath, entryInfo.Name())
+		relativePath := relativeDirectory + entryInfo.Name()
+
+		isSymlink := entryInfo.Mode()&os.ModeSymlink != 0
+		if isSymlink {
+			if !walker.followSymlinks {
+				walker.skipSymlink(relativePath, "since --follow-symlinks is not set")
+				continue
+			}
+			entryInfo, err = os.Stat(entryPath)
+			if err != nil {
+				walker.skipSymlink(relativePath, "since its target cannot be accessed: "+err.Error())
+				continue
+			}
+		}
+
+		switch {
+		case entryInfo.IsDir():
+			if !walker.recursive {
+				continue
+			}
+			entryIdentity, err := getFileIdentity(entryPath, entryInfo)
+			if err != nil {
+				return err
+			}
+			if walker.ancestors[entryIdentity] {
+				walker.skipSymlink(relativePath, "since it leads into a cycle")
+				continue
+			}
+			err = walker.walkDirectory(entryPath, relativePath+"/", entryIdentity)
+			if err != nil {
+				return err
+			}
+		case entryInfo.Mode().IsRegular():
+			if walker.filter != nil && !walker.filter(relativePath) {
+				walker.summary.numFilteredFiles++
+				continue
+			}
+			walker.addFile(relativePath, entryPath, entryInfo)
+		}
+	}
+	return nil
+}
+
+// skipSymlink records a symbolic link which is left out of the walk
+func (walker *localWalker) skipSymlink(relativePath string, reason string) {
+	walker.summary.skippedSymlinks = append(walker.summary.skippedSymlinks, relativePath+" "+reason)
+}
@@ -0,0 +1,51 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package handlers
+
+import (
+	"os"
+	"syscall"
+)
+
+// getFileIdentity returns the volume serial number and the file index of the file, which stand for its device and inode
+// the file is opened to read them, which resolves a link to the file it leads to
+func getFileIdentity(filePath string, fileInfo os.FileInfo) (fileIdentity, error) {
+	pathPointer, err := syscall.UTF16PtrFromString(filePath)
+	if err != nil {
+		return fileIdentity{}, err
+	}
+	// directories can only be opened with backup semantics
+	handle, err := syscall.CreateFile(pathPointer, 0, syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return fileIdentity{}, err
+	}
+	defer syscall.CloseHandle(handle)
+
+	var information syscall.ByHandleFileInformation
+	err = syscall.GetFileInformationByHandle(handle, &information)
+	if err != nil {
+		return fileIdentity{}, err
+	}
+	return fileIdentity{
+		device: uint64(information.VolumeSerialNumber),
+		inode:  uint64(information.FileIndexHigh)<<32 | uint64(information.FileIndexLow),
+	}, nil
+}
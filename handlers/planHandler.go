@@ -0,0 +1,96 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/ste"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// handles the plan show command
+// decodes the job part plan file and prints it either as tables or as JSON
+func HandlePlanShowCommand(commandLineInput common.PlanShowCmdArgsAndFlags) {
+	details, err := ste.DecodeJobPartPlanFile(commandLineInput.FileName)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	if commandLineInput.Output == "json" {
+		detailsJson, err := json.MarshalIndent(details, "", "  ")
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(string(detailsJson))
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, fmt.Sprintf("----------- Job Part Plan %s -----------", details.FileName))
+	fmt.Fprintln(writer, fmt.Sprintf("Version\t%d", details.Version))
+	fmt.Fprintln(writer, fmt.Sprintf("JobId\t%s", details.JobId))
+	fmt.Fprintln(writer, fmt.Sprintf("Part Number\t%d", details.PartNum))
+	fmt.Fprintln(writer, fmt.Sprintf("Final Part\t%t", details.IsFinalPart))
+	fmt.Fprintln(writer, fmt.Sprintf("Priority\t%d", details.Priority))
+	fmt.Fprintln(writer, fmt.Sprintf("TTL After Completion\t%v", time.Duration(details.TTLAfterCompletion)*time.Second))
+	fmt.Fprintln(writer, fmt.Sprintf("Source Type\t%v", details.SrcLocationType))
+	fmt.Fprintln(writer, fmt.Sprintf("Destination Type\t%v", details.DstLocationType))
+	fmt.Fprintln(writer, fmt.Sprintf("Number of Transfers\t%d", details.NumTransfers))
+	fmt.Fprintln(writer, fmt.Sprintf("Number of Filtered Entities\t%d", details.NumFilteredEntities))
+	fmt.Fprintln(writer, fmt.Sprintf("Cancelled\t%t", details.IsCancelled))
+	fmt.Fprintln(writer, fmt.Sprintf("Content Type\t%s", details.ContentType))
+	fmt.Fprintln(writer, fmt.Sprintf("Content Encoding\t%s", details.ContentEncoding))
+	fmt.Fprintln(writer, fmt.Sprintf("Metadata\t%s", details.Metadata))
+	fmt.Fprintln(writer, fmt.Sprintf("Block Size\t%d", details.BlockSize))
+	fmt.Fprintln(writer, fmt.Sprintf("Blob Type\t%v", details.BlobType))
+	writer.Flush()
+
+	for index, transfer := range details.Transfers {
+		fmt.Println()
+		fmt.Fprintln(writer, fmt.Sprintf("----------- Transfer %d -----------", index))
+		fmt.Fprintln(writer, fmt.Sprintf("Source\t%s", transfer.Src))
+		fmt.Fprintln(writer, fmt.Sprintf("Destination\t%s", transfer.Dst))
+		fmt.Fprintln(writer, fmt.Sprintf("Status\t%s", transfer.Status))
+		fmt.Fprintln(writer, fmt.Sprintf("Source Size\t%d", transfer.SourceSize))
+		fmt.Fprintln(writer, fmt.Sprintf("Modified Time\t%s", time.Unix(0, transfer.ModifiedTime).Format(time.RFC3339Nano)))
+		completionTime := "-"
+		if transfer.CompletionTime != 0 {
+			completionTime = time.Unix(0, int64(transfer.CompletionTime)).Format(time.RFC3339)
+		}
+		fmt.Fprintln(writer, fmt.Sprintf("Completion Time\t%s", completionTime))
+		fmt.Fprintln(writer, fmt.Sprintf("Number of Chunks\t%d", len(transfer.Chunks)))
+		writer.Flush()
+
+		if len(transfer.Chunks) == 0 {
+			continue
+		}
+		fmt.Fprintln(writer, "CHUNK\tSTATUS\tBLOCK ID")
+		for chunkIndex, chunk := range transfer.Chunks {
+			fmt.Fprintln(writer, fmt.Sprintf("%d\t%s\t%s", chunkIndex, chunk.Status, chunk.BlockId))
+		}
+		writer.Flush()
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// handles the remove command
+// dispatches the deletion job order (in parts) to the storage engine
+func HandleRemoveCommand(commandLineInput common.RemoveCmdArgsAndFlags) string {
+	jobPartOrder := common.CopyJobPartOrder{}
+	jobPartOrder.LogVerbosity = common.LogSeverity(commandLineInput.LogVerbosity)
+	jobPartOrder.IsaBackgroundOp = commandLineInput.IsaBackgroundOp
+
+	// generate job id
+	uuid, err := newUUID()
+	if err != nil {
+		panic("Failed to generate job id")
+	}
+	jobPartOrder.ID = common.JobID(uuid)
+
+	coordinatorScheduleFunc := generateCoordinatorScheduleFunc()
+	HandleRemoveFromWastore(&commandLineInput, &jobPartOrder, coordinatorScheduleFunc)
+
+	fmt.Println("Job with id", uuid, "has started.")
+	if commandLineInput.IsaBackgroundOp {
+		return uuid
+	}
+	for jobStatus := fetchJobStatus(uuid); jobStatus != common.StatusCompleted && jobStatus != common.StatusCancelled; jobStatus = fetchJobStatus(uuid) {
+		time.Sleep(time.Second)
+	}
+	return uuid
+}
+
+// HandleRemoveFromWastore enumerates the blobs to delete and dispatches them as transfers without destination
+func HandleRemoveFromWastore(commandLineInput *common.RemoveCmdArgsAndFlags,
+	jobPartOrderToFill *common.CopyJobPartOrder,
+	dispatchJobPartOrderFunc func(jobPartOrder *common.CopyJobPartOrder)) {
+
+	// set the source and destination type
+	jobPartOrderToFill.SourceType = common.Blob
+	jobPartOrderToFill.DestinationType = common.Delete
+
+	// attempt to parse the container/blob url
+	sourceUrl, err := url.Parse(commandLineInput.Source)
+	if err != nil {
+		panic(err)
+	}
+	sourcePathParts := strings.SplitN(sourceUrl.Path[1:], "/", 2)
+
+	// source is a single blob, a trailing slash marks a virtual directory instead
+	if len(sourcePathParts) > 1 && sourcePathParts[1] != "" && !strings.HasSuffix(sourcePathParts[1], "/") {
+		singleTask := common.CopyTransfer{
+			Source: sourceUrl.String(),
+		}
+		jobPartOrderToFill.Transfers = []common.CopyTransfer{singleTask}
+		jobPartOrderToFill.IsFinalPart = true
+		jobPartOrderToFill.PartNum = 0
+		dispatchJobPartOrderFunc(jobPartOrderToFill)
+		return
+	}
+
+	// source is a container or a virtual directory inside it
+	prefix := ""
+	if len(sourcePathParts) > 1 {
+		prefix = sourcePathParts[1]
+	}
+	cleanContainerPath := "/" + sourcePathParts[0]
+	sourceUrl.Path = cleanContainerPath
+
+	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{})
+	containerUrl := azblob.NewContainerURL(*sourceUrl, p)
+	filter := newNameFilter(commandLineInput.Include, commandLineInput.Exclude)
+	partNumber := 0
+
+	// iterate over the container
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		// Get a result segment starting with the blob indicated by the current Marker.
+		listBlob, err := containerUrl.ListBlobs(context.Background(), marker, azblob.ListBlobsOptions{Prefix: prefix})
+		if err != nil {
+			log.Fatal(err)
+		}
+		marker = listBlob.NextMarker
+
+		var Transfers []common.CopyTransfer
+		numFilteredBlobs := uint32(0)
+		// Process the blobs returned in this result segment (if the segment is empty, the loop body won't execute)
+		for _, blobInfo := range listBlob.Blobs.Blob {
+			relativeName := strings.TrimPrefix(blobInfo.Name, prefix)
+
+			// blobs inside virtual sub-directories are only deleted when recursive
+			if !commandLineInput.Recursive && strings.Contains(relativeName, "/") {
+				continue
+			}
+			if !filter(relativeName) {
+				numFilteredBlobs++
+				continue
+			}
+			sourceUrl.Path = cleanContainerPath + "/" + blobInfo.Name
+			Transfers = append(Transfers, common.CopyTransfer{Source: sourceUrl.String(), LastModifiedTime: blobInfo.Properties.LastModified})
+		}
+		jobPartOrderToFill.Transfers = Transfers
+		jobPartOrderToFill.NumFilteredEntities = numFilteredBlobs
+		jobPartOrderToFill.PartNum = common.PartNumber(partNumber)
+		partNumber += 1
+		if !marker.NotDone() { // if there is no more segment
+			jobPartOrderToFill.IsFinalPart = true
+		}
+		dispatchJobPartOrderFunc(jobPartOrderToFill)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// HandleCopyFromS3ToWastore copies an S3 object, or the objects of a bucket under a prefix, into Azure Storage
+// the objects under a prefix keep their keys relative to the prefix, sub-prefixes are looked into only when the recursive flag is set
+func HandleCopyFromS3ToWastore(commandLineInput *common.CopyCmdArgsAndFlags,
+	jobPartOrderToFill *common.CopyJobPartOrder,
+	dispatchJobPartOrderFunc func(jobPartOrder *common.CopyJobPartOrder)) {
+	// attempt to parse the source url
+	sourceUrl, err := url.Parse(commandLineInput.Source)
+	if err != nil {
+		panic(err)
+	}
+	source, err := common.ParseS3URL(*sourceUrl)
+	if err != nil {
+		panic(err)
+	}
+	jobPartOrderToFill.SourceType = common.S3
+	jobPartOrderToFill.SourceCredentials = common.GetS3CredentialsFromEnvironment()
+	copyFromS3CompatibleToWastore(commandLineInput, jobPartOrderToFill, dispatchJobPartOrderFunc,
+		common.NewS3Client(jobPartOrderToFill.SourceCredentials), source)
+}
+
+// HandleCopyFromGCSToWastore copies a GCS object, or the objects of a bucket under a prefix, into Azure Storage
+// GCS is listed and read through its S3-compatible XML API, so the objects are enumerated as the ones of S3
+func HandleCopyFromGCSToWastore(commandLineInput *common.CopyCmdArgsAndFlags,
+	jobPartOrderToFill *common.CopyJobPartOrder,
+	dispatchJobPartOrderFunc func(jobPartOrder *common.CopyJobPartOrder)) {
+	// attempt to parse the source url
+	sourceUrl, err := url.Parse(commandLineInput.Source)
+	if err != nil {
+		panic(err)
+	}
+	jobPartOrderToFill.SourceType = common.GCS
+	jobPartOrderToFill.SourceCredentials = common.GetGCSCredentialsFromEnvironment()
+	copyFromS3CompatibleToWastore(commandLineInput, jobPartOrderToFill, dispatchJobPartOrderFunc,
+		common.NewGCSClient(jobPartOrderToFill.SourceCredentials), common.ParseGCSURL(*sourceUrl))
+}
+
+// copyFromS3CompatibleToWastore enumerates the objects of an S3-compatible source into the job, whose source type is set already
+func copyFromS3CompatibleToWastore(commandLineInput *common.CopyCmdArgsAndFlags,
+	jobPartOrderToFill *common.CopyJobPartOrder,
+	dispatchJobPartOrderFunc func(jobPartOrder *common.CopyJobPartOrder),
+	s3Client common.S3Client, s3Source common.S3URLParts) {
+	jobPartOrderToFill.DestinationType = common.Blob
+
+	// attempt to parse the destination url
+	destinationUrl, err := url.Parse(commandLineInput.Destination)
+	if err != nil {
+		panic(err)
+	}
+
+	// source is a single object
+	if s3Source.Key != "" && !strings.HasSuffix(s3Source.Key, "/") {
+		objectProperties, err := s3Client.GetObjectProperties(context.Background(), s3Source)
+		if err != nil {
+			panic(fmt.Sprintf("cannot get the properties of the source object: %s", err.Error()))
+		}
+
+		// if a container url or a virtual directory is given, must append object name to it
+		if !strings.Contains(destinationUrl.Path[1:], "/") || strings.HasSuffix(destinationUrl.Path, "/") {
+			destinationUrl.Path = fmt.Sprintf("%s/%s", strings.TrimSuffix(destinationUrl.Path, "/"), path.Base(s3Source.Key))
+		}
+
+		sourceObjectUrl := s3Source.URL()
+		jobPartOrderToFill.Transfers = []common.CopyTransfer{{
+			Source:           sourceObjectUrl.String(),
+			Destination:      destinationUrl.String(),
+			LastModifiedTime: objectProperties.LastModified,
+			SourceSize:       objectProperties.Size,
+		}}
+		jobPartOrderToFill.PartNum = 0
+		jobPartOrderToFill.IsFinalPart = true
+		dispatchJobPartOrderFunc(jobPartOrderToFill)
+		return
+	}
+
+	// source is a bucket or a prefix, page through the objects under it
+	sourcePrefix := s3Source.Key
+	delimiter := "/"
+	if commandLineInput.Recursive {
+		delimiter = ""
+	}
+	cleanDestinationContainerPath, destinationPrefix := splitContainerPathAndPrefix(destinationUrl.Path)
+	filter := newNameFilter(commandLineInput.Include, commandLineInput.Exclude)
+	partNumber := 0
+
+	for continuationToken, listingDone := "", false; !listingDone; {
+		listResponse, err := s3Client.ListObjectsV2(context.Background(), s3Source, sourcePrefix, delimiter, continuationToken)
+		if err != nil {
+			panic(fmt.Sprintf("cannot list the source bucket: %s", err.Error()))
+		}
+		continuationToken = listResponse.NextContinuationToken
+		listingDone = !listResponse.IsTruncated
+
+		var Transfers []common.CopyTransfer
+		numFilteredObjects := uint32(0)
+		for _, objectInfo := range listResponse.Contents {
+			relativeKey := strings.TrimPrefix(objectInfo.Key, sourcePrefix)
+
+			// keys ending with a slash mark folders created by S3 consoles, they hold no data
+			if relativeKey == "" || strings.HasSuffix(relativeKey, "/") {
+				continue
+			}
+			if !filter(relativeKey) {
+				numFilteredObjects++
+				continue
+			}
+			s3Object := s3Source
+			s3Object.Key = objectInfo.Key
+			sourceObjectUrl := s3Object.URL()
+			destinationUrl.Path = cleanDestinationContainerPath + "/" + destinationPrefix + relativeKey
+			Transfers = append(Transfers, common.CopyTransfer{
+				Source:           sourceObjectUrl.String(),
+				Destination:      destinationUrl.String(),
+				LastModifiedTime: objectInfo.LastModified,
+				SourceSize:       objectInfo.Size,
+			})
+		}
+		jobPartOrderToFill.Transfers = Transfers
+		jobPartOrderToFill.NumFilteredEntities = numFilteredObjects
+		jobPartOrderToFill.PartNum = common.PartNumber(partNumber)
+		partNumber += 1
+		jobPartOrderToFill.IsFinalPart = listingDone
+		dispatchJobPartOrderFunc(jobPartOrderToFill)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
+	"io"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// numOfStreamWorkers is the number of blocks uploaded, or of ranges downloaded ahead, in parallel while streaming
+// a stream holds at most one more block than this in memory
+const numOfStreamWorkers = 4
+
+// maxNumOfBlocks is the largest number of blocks a block blob can be committed with
+const maxNumOfBlocks = 50000
+
+// HandleUploadFromStdinToWastore uploads the standard input as a block blob, whose size is not known up front
+func HandleUploadFromStdinToWastore(commandLineInput *common.CopyCmdArgsAndFlags) {
+	// attempt to parse the destination url
+	destinationUrl, err := url.Parse(commandLineInput.Destination)
+	if err != nil {
+		panic(err)
+	}
+
+	startTime := time.Now()
+	headers := azblob.BlobHTTPHeaders{ContentType: commandLineInput.ContentType, ContentEncoding: commandLineInput.ContentEncoding}
+	numOfBytes, err := uploadStreamToBlockBlob(os.Stdin, *destinationUrl, streamBlockSize(commandLineInput), headers)
+	if err != nil {
+		panic(fmt.Sprintf("cannot upload the standard input: %s", err.Error()))
+	}
+	printStreamSummary("uploaded", numOfBytes, startTime)
+}
+
+// uploadStreamToBlockBlob uploads everything read from the stream as a block blob and returns the number of bytes uploaded
+// the stream is buffered block by block, each block is uploaded while the next ones are read, and the block list is committed at the end
+func uploadStreamToBlockBlob(stream io.Reader, destinationUrl url.URL, blockSize int64, headers azblob.BlobHTTPHeaders) (int64, error) {
+	blobUrl := azblob.NewBlockBlobURL(destinationUrl, newStreamPipeline())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the first failed upload cancels the others and stops the reading of the stream
+	var uploadErr error
+	var uploadErrOnce sync.Once
+	var uploadsDone sync.WaitGroup
+	workerSlots := make(chan struct{}, numOfStreamWorkers)
+
+	// step 1: read the stream block by block, until it ends
+	var blockIds []string
+	var numOfBytes int64
+	for streamDone := false; !streamDone && ctx.Err() == nil; {
+		block := make([]byte, blockSize)
+		n, err := io.ReadFull(stream, block)
+		if err == io.EOF {
+			break
+		}
+		if err == io.ErrUnexpectedEOF {
+			streamDone = true
+		} else if err != nil {
+			cancel()
+			uploadsDone.Wait()
+			return numOfBytes, err
+		}
+		if len(blockIds) == maxNumOfBlocks {
+			cancel()
+			uploadsDone.Wait()
+			return numOfBytes, fmt.Errorf("the stream is larger than %d blocks of %d bytes, please use a larger block size", maxNumOfBlocks, blockSize)
+		}
+
+		// step 2: upload the block once a worker is free, the ids of the blocks are kept in the order of the stream
+		blockId, err := newUUID()
+		if err != nil {
+			panic(err)
+		}
+		encodedBlockId := base64.StdEncoding.EncodeToString([]byte(blockId))
+		blockIds = append(blockIds, encodedBlockId)
+		numOfBytes += int64(n)
+
+		workerSlots <- struct{}{}
+		uploadsDone.Add(1)
+		go func(encodedBlockId string, block []byte) {
+			defer uploadsDone.Done()
+			defer func() { <-workerSlots }()
+			_, err := blobUrl.PutBlock(ctx, encodedBlockId, bytes.NewReader(block), azblob.LeaseAccessConditions{})
+			if err != nil {
+				uploadErrOnce.Do(func() {
+					uploadErr = err
+					cancel()
+				})
+			}
+		}(encodedBlockId, block[:n])
+	}
+	uploadsDone.Wait()
+	if uploadErr != nil {
+		return numOfBytes, uploadErr
+	}
+
+	// step 3: commit the blocks, an empty stream results in an empty blob
+	_, err := blobUrl.PutBlockList(ctx, blockIds, azblob.Metadata{}, headers, azblob.BlobAccessConditions{})
+	return numOfBytes, err
+}
+
+// HandleDownloadFromWastoreToStdout writes a blob to the standard output, in order
+func HandleDownloadFromWastoreToStdout(commandLineInput *common.CopyCmdArgsAndFlags) {
+	// attempt to parse the source url
+	sourceUrl, err := url.Parse(commandLineInput.Source)
+	if err != nil {
+		panic(err)
+	}
+
+	startTime := time.Now()
+	numOfBytes, err := downloadBlobToStream(*sourceUrl, streamBlockSize(commandLineInput), os.Stdout)
+	if err != nil {
+		panic(fmt.Sprintf("cannot download the source blob to the standard output: %s", err.Error()))
+	}
+	printStreamSummary("downloaded", numOfBytes, startTime)
+}
+
+// downloadBlobToStream writes the blob to the stream in order and returns the number of bytes written
+// the ranges of the blob are downloaded ahead of the one being written, as long as at most numOfStreamWorkers are pending
+func downloadBlobToStream(sourceUrl url.URL, blockSize int64, stream io.Writer) (int64, error) {
+	blobUrl := azblob.NewBlobURL(sourceUrl, newStreamPipeline())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// step 1: get the size of the blob, the ranges are only read from the same version of the blob
+	blobProperties, err := blobUrl.GetPropertiesAndMetadata(ctx, azblob.BlobAccessConditions{})
+	if err != nil {
+		return 0, err
+	}
+	blobSize := blobProperties.ContentLength()
+	accessConditions := azblob.BlobAccessConditions{HTTPAccessConditions: azblob.HTTPAccessConditions{IfMatch: blobProperties.ETag()}}
+
+	// step 2: start the download of the ranges in order, each pending range delivers its data on its own channel
+	type streamRange struct {
+		data []byte
+		err  error
+	}
+	pendingRanges := make(chan chan streamRange, numOfStreamWorkers)
+	go func() {
+		defer close(pendingRanges)
+		for offset := int64(0); offset < blobSize; offset += blockSize {
+			count := blockSize
+			if offset+count > blobSize {
+				count = blobSize - offset
+			}
+			result := make(chan streamRange, 1)
+			select {
+			case pendingRanges <- result:
+			case <-ctx.Done():
+				return
+			}
+			go func(offset int64, count int64) {
+				data := make([]byte, count)
+				get, err := blobUrl.GetBlob(ctx, azblob.BlobRange{Offset: offset, Count: count}, accessConditions, false)
+				if err == nil {
+					_, err = io.ReadFull(get.Body(), data)
+					get.Body().Close()
+				}
+				result <- streamRange{data: data, err: err}
+			}(offset, count)
+		}
+	}()
+
+	// step 3: write the ranges to the stream in order, as they arrive
+	// returning cancels the ranges which are still pending
+	numOfBytes := int64(0)
+	for result := range pendingRanges {
+		streamRange := <-result
+		if streamRange.err != nil {
+			return numOfBytes, streamRange.err
+		}
+		n, err := stream.Write(streamRange.data)
+		numOfBytes += int64(n)
+		if err != nil {
+			return numOfBytes, err
+		}
+	}
+	return numOfBytes, nil
+}
+
+// streamBlockSize returns the size of the blocks a stream is transferred in
+func streamBlockSize(commandLineInput *common.CopyCmdArgsAndFlags) int64 {
+	if commandLineInput.BlockSize == 0 {
+		return common.DefaultBlockSize
+	}
+	return int64(commandLineInput.BlockSize)
+}
+
+func newStreamPipeline() azblob.Pipeline {
+	return azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{
+		Retry: azblob.RetryOptions{
+			Policy:        azblob.RetryPolicyExponential,
+			MaxTries:      3,
+			TryTimeout:    time.Second * 60,
+			RetryDelay:    time.Second * 1,
+			MaxRetryDelay: time.Second * 3,
+		},
+	})
+}
+
+// printStreamSummary prints how much was streamed to the standard error, since the standard output may carry the stream
+func printStreamSummary(direction string, numOfBytes int64, startTime time.Time) {
+	fmt.Fprintln(os.Stderr, fmt.Sprintf("%s %d bytes in %v", direction, numOfBytes, time.Since(startTime)))
+}
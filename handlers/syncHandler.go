@@ -0,0 +1,289 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// syncEntityProperties holds the properties which decide whether an entity changed since the last sync
+type syncEntityProperties struct {
+	lastModifiedTime time.Time
+	size             int64
+}
+
+// handles the sync command
+// dispatches the new or changed entities (in parts) to the storage engine
+func HandleSyncCommand(commandLineInput common.SyncCmdArgsAndFlags) string {
+	jobPartOrder := common.CopyJobPartOrder{}
+	jobPartOrder.OptionalAttributes = common.BlobTransferAttributes{BlockSizeinBytes: commandLineInput.BlockSize}
+	jobPartOrder.LogVerbosity = common.LogSeverity(commandLineInput.LogVerbosity)
+	jobPartOrder.IsaBackgroundOp = commandLineInput.IsaBackgroundOp
+
+	// generate job id
+	uuid, err := newUUID()
+	if err != nil {
+		panic("Failed to generate job id")
+	}
+	jobPartOrder.ID = common.JobID(uuid)
+
+	coordinatorScheduleFunc := generateCoordinatorScheduleFunc()
+	if commandLineInput.SourceType == common.Local && commandLineInput.DestinationType == common.Blob {
+		HandleSyncFromLocalToWastore(&commandLineInput, &jobPartOrder, coordinatorScheduleFunc)
+	} else if commandLineInput.SourceType == common.Blob && commandLineInput.DestinationType == common.Local {
+		HandleSyncFromWastoreToLocal(&commandLineInput, &jobPartOrder, coordinatorScheduleFunc)
+	}
+
+	fmt.Println("Job with id", uuid, "has started.")
+	if commandLineInput.IsaBackgroundOp {
+		return uuid
+	}
+	for jobStatus := fetchJobStatus(uuid); jobStatus != common.StatusCompleted && jobStatus != common.StatusCancelled; jobStatus = fetchJobStatus(uuid) {
+		time.Sleep(time.Second)
+	}
+	return uuid
+}
+
+// HandleSyncFromLocalToWastore compares the local directory with the container
+// and dispatches the upload of new or changed files, followed by the deletion of blobs which do not exist locally anymore
+func HandleSyncFromLocalToWastore(commandLineInput *common.SyncCmdArgsAndFlags,
+	jobPartOrderToFill *common.CopyJobPartOrder,
+	dispatchJobPartOrderFunc func(jobPartOrder *common.CopyJobPartOrder)) {
+
+	sourceFileInfo, err := os.Stat(commandLineInput.Source)
+	// since source was already validated, it would be surprising if file/directory cannot be accessed at this point
+	if err != nil {
+		panic("cannot access source, not a valid local file system path")
+	}
+	if !sourceFileInfo.IsDir() {
+		panic("source should be a directory")
+	}
+
+	// attempt to parse the destination url
+	destinationUrl, err := url.Parse(commandLineInput.Destination)
+	if err != nil {
+		panic(err)
+	}
+	cleanContainerPath, prefix := splitContainerPathAndPrefix(destinationUrl.Path)
+
+	// list the destination, so that each local file can be compared against its blob
+	destinationUrl.Path = cleanContainerPath
+	destinationBlobs := make(map[string]syncEntityProperties)
+	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{})
+	containerUrl := azblob.NewContainerURL(*destinationUrl, p)
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		// Get a result segment starting with the blob indicated by the current Marker.
+		listBlob, err := containerUrl.ListBlobs(context.Background(), marker, azblob.ListBlobsOptions{Prefix: prefix})
+		if err != nil {
+			log.Fatal(err)
+		}
+		marker = listBlob.NextMarker
+
+		for _, blobInfo := range listBlob.Blobs.Blob {
+			relativeName := strings.TrimPrefix(blobInfo.Name, prefix)
+			if !commandLineInput.Recursive && strings.Contains(relativeName, "/") {
+				continue
+			}
+			destinationBlobs[relativeName] = syncEntityProperties{blobInfo.Properties.LastModified, *blobInfo.Properties.ContentLength}
+		}
+	}
+
+	// walk the source, a file is uploaded when its blob is missing, has a different size or is older than the file
+	var copyTransfers []common.CopyTransfer
+	// symbolic links and other special files are not synced
+	_, err = walkLocalFiles(commandLineInput.Source, commandLineInput.Recursive, false, nil, func(relativeName string, filePath string, fileInfo os.FileInfo) {
+		blobProperties, exists := destinationBlobs[relativeName]
+		delete(destinationBlobs, relativeName)
+		if exists && blobProperties.size == fileInfo.Size() && !fileInfo.ModTime().After(blobProperties.lastModifiedTime) {
+			return
+		}
+
+		destinationUrl.Path = cleanContainerPath + "/" + prefix + relativeName
+		copyTransfers = append(copyTransfers, common.CopyTransfer{
+			Source:           filePath,
+			Destination:      destinationUrl.String(),
+			LastModifiedTime: fileInfo.ModTime(),
+			SourceSize:       fileInfo.Size(),
+		})
+	})
+	if err != nil {
+		panic(fmt.Sprintf("cannot walk the source directory: %s", err))
+	}
+
+	// the blobs left over do not exist locally anymore
+	var deleteTransfers []common.CopyTransfer
+	if commandLineInput.DeleteDestination {
+		for relativeName, blobProperties := range destinationBlobs {
+			destinationUrl.Path = cleanContainerPath + "/" + prefix + relativeName
+			deleteTransfers = append(deleteTransfers, common.CopyTransfer{
+				Source:           destinationUrl.String(),
+				LastModifiedTime: blobProperties.lastModifiedTime,
+			})
+		}
+	}
+
+	dispatchSyncTransfers(jobPartOrderToFill, common.Local, common.Blob, copyTransfers, deleteTransfers, dispatchJobPartOrderFunc)
+}
+
+// HandleSyncFromWastoreToLocal compares the container with the local directory
+// and dispatches the download of new or changed blobs, followed by the deletion of files which are not in the container anymore
+func HandleSyncFromWastoreToLocal(commandLineInput *common.SyncCmdArgsAndFlags,
+	jobPartOrderToFill *common.CopyJobPartOrder,
+	dispatchJobPartOrderFunc func(jobPartOrder *common.CopyJobPartOrder)) {
+
+	// attempt to parse the container/blob url
+	sourceUrl, err := url.Parse(commandLineInput.Source)
+	if err != nil {
+		panic(err)
+	}
+	cleanContainerPath, prefix := splitContainerPathAndPrefix(sourceUrl.Path)
+
+	// create the destination if it does not exist
+	err = os.MkdirAll(commandLineInput.Destination, os.ModePerm)
+	if err != nil {
+		panic("failed to create the destination on the local file system")
+	}
+
+	// walk the destination, so that each blob can be compared against its local file
+	destinationFiles := make(map[string]syncEntityProperties)
+	_, err = walkLocalFiles(commandLineInput.Destination, commandLineInput.Recursive, false, nil, func(relativeName string, filePath string, fileInfo os.FileInfo) {
+		destinationFiles[relativeName] = syncEntityProperties{fileInfo.ModTime(), fileInfo.Size()}
+	})
+	if err != nil {
+		panic(fmt.Sprintf("cannot walk the destination directory: %s", err))
+	}
+
+	// list the source, a blob is downloaded when its file is missing, has a different size or is older than the blob
+	sourceUrl.Path = cleanContainerPath
+	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{})
+	containerUrl := azblob.NewContainerURL(*sourceUrl, p)
+	var copyTransfers []common.CopyTransfer
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		// Get a result segment starting with the blob indicated by the current Marker.
+		listBlob, err := containerUrl.ListBlobs(context.Background(), marker, azblob.ListBlobsOptions{Prefix: prefix})
+		if err != nil {
+			log.Fatal(err)
+		}
+		marker = listBlob.NextMarker
+
+		for _, blobInfo := range listBlob.Blobs.Blob {
+			relativeName := strings.TrimPrefix(blobInfo.Name, prefix)
+			if !commandLineInput.Recursive && strings.Contains(relativeName, "/") || isDirectoryMarkerBlob(blobInfo.Name) {
+				continue
+			}
+			blobSize := *blobInfo.Properties.ContentLength
+			fileProperties, exists := destinationFiles[relativeName]
+			delete(destinationFiles, relativeName)
+			if exists && fileProperties.size == blobSize && !blobInfo.Properties.LastModified.After(fileProperties.lastModifiedTime) {
+				continue
+			}
+
+			// the virtual directories of the blob become local directories, created by the storage engine
+			destinationPath, err := joinUnderDirectory(commandLineInput.Destination, relativeName)
+			if err != nil {
+				fmt.Println("Skipped blob", blobInfo.Name, "since", err.Error())
+				continue
+			}
+
+			sourceUrl.Path = cleanContainerPath + "/" + blobInfo.Name
+			copyTransfers = append(copyTransfers, common.CopyTransfer{
+				Source:           sourceUrl.String(),
+				Destination:      destinationPath,
+				LastModifiedTime: blobInfo.Properties.LastModified,
+				SourceSize:       blobSize,
+			})
+		}
+	}
+
+	// the files left over do not exist in the container anymore
+	var deleteTransfers []common.CopyTransfer
+	if commandLineInput.DeleteDestination {
+		for relativeName, fileProperties := range destinationFiles {
+			deleteTransfers = append(deleteTransfers, common.CopyTransfer{
+				Source:           filepath.Join(commandLineInput.Destination, filepath.FromSlash(relativeName)),
+				LastModifiedTime: fileProperties.lastModifiedTime,
+			})
+		}
+	}
+
+	dispatchSyncTransfers(jobPartOrderToFill, common.Blob, common.Local, copyTransfers, deleteTransfers, dispatchJobPartOrderFunc)
+}
+
+// dispatchSyncTransfers dispatches the copies followed by the deletions of a sync job in parts of NumOfFilesPerUploadJobPart transfers
+// the deletions remove entities at the destination, so their source type is the destination type of the copies
+func dispatchSyncTransfers(jobPartOrderToFill *common.CopyJobPartOrder, sourceType common.LocationType, destinationType common.LocationType,
+	copyTransfers []common.CopyTransfer, deleteTransfers []common.CopyTransfer,
+	dispatchJobPartOrderFunc func(jobPartOrder *common.CopyJobPartOrder)) {
+
+	partNumber := 0
+	dispatchPart := func(transfers []common.CopyTransfer, isFinalPart bool) {
+		jobPartOrderToFill.Transfers = transfers
+		jobPartOrderToFill.PartNum = common.PartNumber(partNumber)
+		jobPartOrderToFill.IsFinalPart = isFinalPart
+		partNumber += 1
+		dispatchJobPartOrderFunc(jobPartOrderToFill)
+	}
+
+	jobPartOrderToFill.SourceType = sourceType
+	jobPartOrderToFill.DestinationType = destinationType
+	for startIndex := 0; startIndex < len(copyTransfers); startIndex += NumOfFilesPerUploadJobPart {
+		endIndex := startIndex + NumOfFilesPerUploadJobPart
+		if endIndex > len(copyTransfers) {
+			endIndex = len(copyTransfers)
+		}
+		dispatchPart(copyTransfers[startIndex:endIndex], endIndex == len(copyTransfers) && len(deleteTransfers) == 0)
+	}
+
+	jobPartOrderToFill.SourceType = destinationType
+	jobPartOrderToFill.DestinationType = common.Delete
+	for startIndex := 0; startIndex < len(deleteTransfers); startIndex += NumOfFilesPerUploadJobPart {
+		endIndex := startIndex + NumOfFilesPerUploadJobPart
+		if endIndex > len(deleteTransfers) {
+			endIndex = len(deleteTransfers)
+		}
+		dispatchPart(deleteTransfers[startIndex:endIndex], endIndex == len(deleteTransfers))
+	}
+
+	// source and destination are already in sync, the final part still has to be ordered for the job to complete
+	if len(copyTransfers) == 0 && len(deleteTransfers) == 0 {
+		dispatchPart([]common.CopyTransfer{}, true)
+	}
+}
+
+// splitContainerPathAndPrefix splits the path of a container or virtual directory url
+// into the path of the container and the prefix of the blob names, which ends with a slash unless empty
+func splitContainerPathAndPrefix(urlPath string) (cleanContainerPath string, prefix string) {
+	pathParts := strings.SplitN(strings.TrimPrefix(urlPath, "/"), "/", 2)
+	cleanContainerPath = "/" + pathParts[0]
+	if len(pathParts) > 1 && pathParts[1] != "" {
+		prefix = strings.TrimSuffix(pathParts[1], "/") + "/"
+	}
+	return cleanContainerPath, prefix
+}
@@ -0,0 +1,60 @@
+package ste
+
+import (
+	"context"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
+	"net/http"
+)
+
+type blobDelete struct{}
+
+// this function schedules a single chunkMsg which deletes the source blob of the transfer
+func (blobDelete blobDelete) prologue(transfer TransferMsgDetail, chunkChannel chan<- ChunkMsg) {
+	// step 1: create pipeline for the blob to delete
+	blobUrl := newTransferBlobURL(transfer.Source)
+
+	// step 2: schedule the deletion, a deletion has no chunks to split into
+	scheduleChunkMsg(ChunkMsg{
+		jobId:            transfer.JobId,
+		partNumber:       transfer.PartNumber,
+		jPartPlanInfoMap: transfer.JobHandlerMap,
+		doTransfer:       generateDeleteFunc(
+			transfer.JobId,
+			transfer.PartNumber,
+			transfer.TransferId,
+			blobUrl,
+			transfer.TransferCtx,
+			transfer.JobHandlerMap),
+	}, chunkChannel)
+}
+
+// this generates a function which deletes a blob along with its snapshots
+func generateDeleteFunc(jobId common.JobID, partNum common.PartNumber, transferId uint32, blobURL azblob.BlobURL,
+	ctx context.Context, jPartPlanInfoMap *JobPartPlanInfoMap) chunkFunc {
+	return func(workerId int) {
+		logger := getLoggerFromJobPartPlanInfo(jobId, partNum, jPartPlanInfoMap)
+		transferIdentifierStr := fmt.Sprintf("jobId %s and partNum %d and transferId %d", jobId, partNum, transferId)
+
+		// the deletion of a cancelled transfer is not performed anymore
+		if ctx.Err() != nil {
+			logger.Debug("worker %d is skipping delete Transfer job with %s since the transfer was cancelled", workerId, transferIdentifierStr)
+			return
+		}
+
+		_, err := blobURL.Delete(ctx, azblob.DeleteSnapshotsOptionInclude, azblob.BlobAccessConditions{})
+		if err != nil {
+			// a blob which does not exist anymore has nothing left to delete
+			if storageErr, ok := err.(azblob.StorageError); !ok || storageErr.Response() == nil || storageErr.Response().StatusCode != http.StatusNotFound {
+				logger.Error("worker %d failed to delete the blob of transfer job with %s due to error %s", workerId, transferIdentifierStr, err.Error())
+				updateTransferStatus(jobId, partNum, transferId, common.TransferStatusFailed, jPartPlanInfoMap)
+				return
+			}
+			logger.Info("worker %d found the blob of transfer job with %s already deleted", workerId, transferIdentifierStr)
+		}
+
+		logger.Debug("worker %d is concluding delete Transfer job with %s", workerId, transferIdentifierStr)
+		updateTransferStatus(jobId, partNum, transferId, common.TransferStatusComplete, jPartPlanInfoMap)
+	}
+}
@@ -0,0 +1,190 @@
+package ste
+
+import (
+	"context"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// copyStatusPollInterval is the interval at which the status of a server side copy is checked
+const copyStatusPollInterval = 2 * time.Second
+
+// blobToBlob copies a blob server side with Start Copy
+// Put Block From URL is not part of the 2016-05-31 service version, so the chunks of the transfer
+// are not copied individually; they are marked complete as the reported copy progress covers them
+type blobToBlob struct{}
+
+// this function starts the server side copy of the source blob into the destination blob
+// and schedules the chunkMsg which tracks the progress of the copy
+func (blobToBlob blobToBlob) prologue(transfer TransferMsgDetail, chunkChannel chan<- ChunkMsg) {
+	logger := getLoggerFromJobPartPlanInfo(transfer.JobId, transfer.PartNumber, transfer.JobHandlerMap)
+	transferIdentifierStr := fmt.Sprintf("jobId %s and partNum %d and transferId %d", transfer.JobId, transfer.PartNumber, transfer.TransferId)
+
+	// step 1: create pipeline for the source and destination blobs
+	sourceUrl, _ := url.Parse(transfer.Source)
+	destinationBlobUrl := newTransferBlobURL(transfer.Destination)
+
+	// step 2: find the transfer in the job part plan, which records when the source was last modified
+	jHandler, err := getJobPartInfoHandlerFromMap(transfer.JobId, transfer.PartNumber, transfer.JobHandlerMap)
+	if err != nil {
+		panic(err)
+	}
+	transferHeader := jHandler.Transfer(transfer.TransferId)
+	numOfChunks := transferHeader.ChunkNum
+
+	// step 3: start the copy, unless the destination holds a copy of the source already, pending or succeeded
+	// since the copy id is not persisted, a resumed transfer keeps tracking the copy it started before
+	var copyId string
+	destinationProperties, err := destinationBlobUrl.GetPropertiesAndMetadata(transfer.TransferCtx, azblob.BlobAccessConditions{})
+	if err == nil && isCopyOfSource(destinationProperties, *sourceUrl, time.Unix(0, transferHeader.ModifiedTime)) {
+		logger.Debug("resuming the tracking of the copy %s of Transfer job with %s", destinationProperties.CopyID(), transferIdentifierStr)
+		copyId = destinationProperties.CopyID()
+	} else {
+		copyResponse, err := destinationBlobUrl.StartCopy(transfer.TransferCtx, *sourceUrl, azblob.Metadata{}, azblob.BlobAccessConditions{}, azblob.BlobAccessConditions{})
+		if err != nil {
+			logger.Error("failed to start the copy of Transfer job with %s due to error %s", transferIdentifierStr, err.Error())
+			updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+			return
+		}
+		copyId = copyResponse.CopyID()
+	}
+
+	// step 4: find the chunks marked copied before the job got resumed
+	chunksCopied := uint16(0)
+	for ; chunksCopied < numOfChunks; chunksCopied++ {
+		if chunkStatus, _ := getChunkStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, chunksCopied, transfer.JobHandlerMap); chunkStatus != ChunkTransferStatusComplete {
+			break
+		}
+	}
+
+	// step 5: schedule the tracking of the copy
+	var chunkMsg ChunkMsg
+	chunkMsg = ChunkMsg{
+		jobId:            transfer.JobId,
+		partNumber:       transfer.PartNumber,
+		jPartPlanInfoMap: transfer.JobHandlerMap,
+		doTransfer: generateCopyStatusCheckFunc(
+			transfer.JobId,
+			transfer.PartNumber,
+			transfer.TransferId,
+			int64(transfer.ChunkSize),
+			numOfChunks,
+			&chunksCopied,
+			destinationBlobUrl,
+			copyId,
+			transfer.TransferCtx,
+			func() {
+				// the copy is checked again after the poll interval, without holding a worker in the meantime
+				// the chunkMsg is counted in flight while it waits, so that its job is not cleaned meanwhile
+				if !acquireChunkMsgInFlight(chunkMsg) {
+					return
+				}
+				go func() {
+					time.Sleep(copyStatusPollInterval)
+					chunkChannel <- chunkMsg
+				}()
+			},
+			transfer.JobHandlerMap),
+	}
+	scheduleChunkMsg(chunkMsg, chunkChannel)
+}
+
+// this generates a function which checks the status of a server side copy
+// the chunks whose range has been copied are marked complete, and the transfer concludes once the copy is over
+// reschedule is called to check the copy again while it is pending, the copy is aborted once the transfer is cancelled
+func generateCopyStatusCheckFunc(jobId common.JobID, partNum common.PartNumber, transferId uint32, chunkSize int64, numOfChunks uint16, chunksCopied *uint16,
+	destinationBlobUrl azblob.BlobURL, copyId string, ctx context.Context, reschedule func(), jPartPlanInfoMap *JobPartPlanInfoMap) chunkFunc {
+	return func(workerId int) {
+		logger := getLoggerFromJobPartPlanInfo(jobId, partNum, jPartPlanInfoMap)
+		transferIdentifierStr := fmt.Sprintf("jobId %s and partNum %d and transferId %d", jobId, partNum, transferId)
+
+		// the copy of a cancelled transfer is aborted and not tracked anymore
+		// the context of the transfer is done, so the copy is aborted with a context of its own
+		if ctx.Err() != nil {
+			logger.Debug("worker %d is aborting the copy %s of Transfer job with %s since the transfer was cancelled", workerId, copyId, transferIdentifierStr)
+			_, err := destinationBlobUrl.AbortCopy(context.Background(), copyId, azblob.LeaseAccessConditions{})
+			if err != nil {
+				// the copy may have completed meanwhile, in which case there is nothing to abort
+				logger.Info("failed to abort the copy %s of Transfer job with %s due to error %s", copyId, transferIdentifierStr, err.Error())
+			}
+			return
+		}
+
+		destinationProperties, err := destinationBlobUrl.GetPropertiesAndMetadata(ctx, azblob.BlobAccessConditions{})
+		if err != nil {
+			logger.Error("worker %d failed to get the copy status of Transfer job with %s due to error %s", workerId, transferIdentifierStr, err.Error())
+			updateTransferStatus(jobId, partNum, transferId, common.TransferStatusFailed, jPartPlanInfoMap)
+			return
+		}
+
+		copiedBytes, totalBytes := parseCopyProgress(destinationProperties.CopyProgress())
+		switch destinationProperties.CopyStatus() {
+		case azblob.CopyStatusSuccess:
+			updateThroughputCounter(updateCopiedChunks(jobId, partNum, transferId, chunkSize, numOfChunks, totalBytes, totalBytes, chunksCopied, jPartPlanInfoMap))
+			logger.Debug("worker %d is concluding copy Transfer job with %s", workerId, transferIdentifierStr)
+			updateTransferStatus(jobId, partNum, transferId, common.TransferStatusComplete, jPartPlanInfoMap)
+		case azblob.CopyStatusPending:
+			updateThroughputCounter(updateCopiedChunks(jobId, partNum, transferId, chunkSize, numOfChunks, copiedBytes, totalBytes, chunksCopied, jPartPlanInfoMap))
+			reschedule()
+		default:
+			logger.Error("worker %d found the copy of Transfer job with %s %s: %s", workerId, transferIdentifierStr,
+				destinationProperties.CopyStatus(), destinationProperties.CopyStatusDescription())
+			updateTransferStatus(jobId, partNum, transferId, common.TransferStatusFailed, jPartPlanInfoMap)
+		}
+	}
+}
+
+// updateCopiedChunks marks the chunks whose range lies within the copied bytes complete
+// chunksCopied holds the number of chunks marked complete so far
+// returns the number of bytes of the chunks newly marked complete
+func updateCopiedChunks(jobId common.JobID, partNum common.PartNumber, transferId uint32, chunkSize int64, numOfChunks uint16,
+	copiedBytes int64, totalBytes int64, chunksCopied *uint16, jPartPlanInfoMap *JobPartPlanInfoMap) int64 {
+	var newlyCopiedBytes int64 = 0
+	for ; *chunksCopied < numOfChunks; *chunksCopied++ {
+		chunkStart := int64(*chunksCopied) * chunkSize
+		chunkEnd := chunkStart + chunkSize
+		if chunkEnd > totalBytes {
+			chunkEnd = totalBytes
+		}
+		if chunkEnd > copiedBytes || chunkStart >= chunkEnd {
+			break
+		}
+		updateChunkInfo(jobId, partNum, transferId, *chunksCopied, [128 / 8]byte{}, ChunkTransferStatusComplete, jPartPlanInfoMap)
+		newlyCopiedBytes += chunkEnd - chunkStart
+	}
+	return newlyCopiedBytes
+}
+
+// isCopyOfSource returns true if the destination blob holds a copy of the source which is pending, or which
+// succeeded after the source was last modified
+// the query of the copy source is not compared, since the service does not return the signature of a sas
+func isCopyOfSource(destinationProperties *azblob.BlobsGetPropertiesResponse, sourceUrl url.URL, sourceModifiedTime time.Time) bool {
+	copySource, err := url.Parse(destinationProperties.CopySource())
+	if err != nil || copySource.Host != sourceUrl.Host || copySource.Path != sourceUrl.Path {
+		return false
+	}
+	switch destinationProperties.CopyStatus() {
+	case azblob.CopyStatusPending:
+		return true
+	case azblob.CopyStatusSuccess:
+		return !destinationProperties.CopyCompletionTime().Before(sourceModifiedTime)
+	default:
+		return false
+	}
+}
+
+// parseCopyProgress parses the copy progress of a blob, given as "<bytes copied>/<total bytes>"
+func parseCopyProgress(copyProgress string) (copiedBytes int64, totalBytes int64) {
+	progressParts := strings.Split(copyProgress, "/")
+	if len(progressParts) != 2 {
+		return 0, 0
+	}
+	copiedBytes, _ = strconv.ParseInt(progressParts[0], 10, 64)
+	totalBytes, _ = strconv.ParseInt(progressParts[1], 10, 64)
+	return copiedBytes, totalBytes
+}
@@ -3,7 +3,7 @@ package ste
 import (
 	"context"
 	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
-	"net/url"
+	"os"
 	"time"
 	"github.com/edsrzf/mmap-go"
 	"io"
@@ -12,77 +12,97 @@ import (
 	"fmt"
 )
 
-type blobToLocal struct{
-	// count the number of chunks that are done
-	count uint32
-}
+type blobToLocal struct{}
 
 func (blobToLocal blobToLocal) prologue(transfer TransferMsgDetail, chunkChannel chan<- ChunkMsg) {
-	// step 1: get blob size
-	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{
-		Retry: azblob.RetryOptions{
-			Policy:        azblob.RetryPolicyExponential,
-			MaxTries:      3,
-			TryTimeout:    time.Second * 60,
-			RetryDelay:    time.Second * 1,
-			MaxRetryDelay: time.Second * 3,
-		},
-	})
-	u, _ := url.Parse(transfer.Source)
-	blobUrl := azblob.NewBlobURL(*u, p)
-	blobSize := getBlobSize(blobUrl)
-
-	// step 2: prep local file before download starts
-	memoryMappedFile := createAndMemoryMapFile(transfer.Destination, blobSize)
-
-	// step 3: go through the blob range and schedule download chunk jobs/msgs
-	downloadChunkSize := int64(transfer.ChunkSize)
-
-	blockIdCount := int32(0)
-	for startIndex := int64(0); startIndex < blobSize; startIndex += downloadChunkSize {
-		adjustedChunkSize := downloadChunkSize
-
-		// compute exact size of the chunk
-		if startIndex+downloadChunkSize > blobSize {
-			adjustedChunkSize = blobSize - startIndex
+	logger := getLoggerFromJobPartPlanInfo(transfer.JobId, transfer.PartNumber, transfer.JobHandlerMap)
+	transferIdentifierStr := fmt.Sprintf("jobId %s and partNum %d and transferId %d", transfer.JobId, transfer.PartNumber, transfer.TransferId)
+
+	// step 1: get blob size, the chunks downloaded before the job got resumed are only reused if the blob did not change meanwhile
+	blobUrl := newTransferBlobURL(transfer.Source)
+	blobProperties, err := blobUrl.GetPropertiesAndMetadata(transfer.TransferCtx, azblob.BlobAccessConditions{})
+	if err == nil {
+		err = restartTransferIfSourceChanged(transfer, blobProperties.ContentLength(), blobProperties.LastModified())
+	}
+	if err != nil {
+		logger.Error("failed to get the properties of the source of Transfer job with %s due to error %s", transferIdentifierStr, err.Error())
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+		return
+	}
+	blobSize := blobProperties.ContentLength()
+
+	// step 2: find the chunks which were already downloaded before the job got resumed
+	numOfChunks := computeNumOfChunks(blobSize, int64(transfer.ChunkSize))
+	chunksDownloaded, numOfChunksDownloaded := getCompletedChunks(transfer, numOfChunks)
+
+	// step 3: an empty blob only needs the local file to be created
+	if blobSize == 0 {
+		destinationFile, err := openFile(transfer.Destination, os.O_RDWR|os.O_CREATE|os.O_TRUNC)
+		if err != nil {
+			logger.Error("failed to create the destination of Transfer job with %s due to error %s", transferIdentifierStr, err.Error())
+			updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+			return
 		}
+		destinationFile.Close()
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusComplete, transfer.JobHandlerMap)
+		return
+	}
+
+	// step 4: prep local file before download starts
+	memoryMappedFile, refCount, ok := mapDestinationFile(transfer, blobSize, chunksDownloaded, &numOfChunksDownloaded)
+	if !ok {
+		return
+	}
+
+	// step 5: conclude the transfer right away if every chunk was downloaded already
+	if numOfChunksDownloaded == numOfChunks {
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusComplete, transfer.JobHandlerMap)
+		refCount.done()
+		return
+	}
 
-		// schedule the download chunk job
-		chunkChannel <- ChunkMsg{
-			doTransfer: generateDownloadFunc(
+	// step 6: go through the blob range and schedule download chunk jobs/msgs for the chunks not downloaded yet
+	scheduleChunks(transfer, chunkChannel, blobSize, chunksDownloaded, refCount,
+		func(chunkId int32, startIndex int64, chunkSize int64) chunkFunc {
+			return generateDownloadFunc(
 				transfer.JobId,
 				transfer.PartNumber,
 				transfer.TransferId,
-				blockIdCount, // serves as index of chunk
-				computeNumOfChunks(blobSize, downloadChunkSize),
-				adjustedChunkSize,
+				chunkId,
+				numOfChunks,
+				chunkSize,
 				startIndex,
 				blobUrl,
 				memoryMappedFile,
+				refCount,
 				transfer.TransferCtx,
 				transfer.TransferCancelFunc,
-				&blobToLocal.count, transfer.JobHandlerMap),
-		}
-		blockIdCount += 1
-	}
+				&numOfChunksDownloaded, transfer.JobHandlerMap)
+		})
 }
 
 // this generates a function which performs the downloading of a single chunk
 func generateDownloadFunc(jobId common.JobID, partNum common.PartNumber,transferId uint32, chunkId int32, totalNumOfChunks uint32, chunkSize int64, startIndex int64,
-	blobURL azblob.BlobURL, memoryMappedFile mmap.MMap, ctx context.Context, cancelTransfer func(), progressCount *uint32, jPartPlanInfoMap *JobPartPlanInfoMap) chunkFunc {
+	blobURL azblob.BlobURL, memoryMappedFile mmap.MMap, refCount *chunkRefCount, ctx context.Context, cancelTransfer func(), progressCount *uint32, jPartPlanInfoMap *JobPartPlanInfoMap) chunkFunc {
 	return func(workerId int) {
 		logger := getLoggerFromJobPartPlanInfo(jobId, partNum, jPartPlanInfoMap)
 		transferIdentifierStr := fmt.Sprintf("jobId %s and partNum %d and transferId %d", jobId, partNum, transferId)
+		defer refCount.done()
 
 		//fmt.Println("Worker", workerId, "is processing download CHUNK job with", transferIdentifierStr)
 
+		if isChunkCancelled(ctx, workerId, chunkId, transferIdentifierStr, logger) {
+			return
+		}
+
 		// step 1: perform get
+		startTime := time.Now()
 		get, err := blobURL.GetBlob(ctx, azblob.BlobRange{Offset: startIndex, Count: chunkSize}, azblob.BlobAccessConditions{}, false)
 		if err != nil {
 			// cancel entire transfer because this chunk has failed
 			cancelTransfer()
 			logger.Debug("worker %d is canceling Chunk job with %s and chunkId %d because startIndex of %d has failed", workerId, transferIdentifierStr, chunkId, startIndex)
-			updateChunkInfo(jobId, partNum, transferId, uint16(chunkId), ChunkTransferStatusFailed, jPartPlanInfoMap)
+			updateChunkInfo(jobId, partNum, transferId, uint16(chunkId), [128 / 8]byte{}, ChunkTransferStatusFailed, jPartPlanInfoMap)
 			updateTransferStatus(jobId, partNum, transferId, common.TransferStatusFailed, jPartPlanInfoMap)
 			return
 		}
@@ -95,12 +115,13 @@ func generateDownloadFunc(jobId common.JobID, partNum common.PartNumber,transfer
 			cancelTransfer()
 			logger.Debug("worker %d is canceling Chunk job with %s and chunkId %d because writing to file for startIndex of %d has failed", workerId, transferIdentifierStr, chunkId, startIndex)
 			//fmt.Println("Worker", workerId, "is canceling CHUNK job with", transferIdentifierStr, "and chunkID", chunkId, "because writing to file for startIndex of", startIndex, "has failed")
-			updateChunkInfo(jobId, partNum, transferId, uint16(chunkId), ChunkTransferStatusFailed, jPartPlanInfoMap)
+			updateChunkInfo(jobId, partNum, transferId, uint16(chunkId), [128 / 8]byte{}, ChunkTransferStatusFailed, jPartPlanInfoMap)
 			updateTransferStatus(jobId, partNum, transferId, common.TransferStatusFailed,  jPartPlanInfoMap)
 			return
 		}
 
-		updateChunkInfo(jobId, partNum, transferId, uint16(chunkId), ChunkTransferStatusComplete, jPartPlanInfoMap)
+		updateChunkInfo(jobId, partNum, transferId, uint16(chunkId), [128 / 8]byte{}, ChunkTransferStatusComplete, jPartPlanInfoMap)
+		recordChunkLatency(jobId, partNum, time.Since(startTime), jPartPlanInfoMap)
 		updateThroughputCounter(chunkSize)
 
 		// step 3: check if this is the last chunk
@@ -110,12 +131,6 @@ func generateDownloadFunc(jobId common.JobID, partNum common.PartNumber,transfer
 			//fmt.Println("Worker", workerId, "is concluding download TRANSFER job with", transferIdentifierStr, "after processing chunkId", chunkId)
 
 			updateTransferStatus(jobId, partNum, transferId, common.TransferStatusComplete, jPartPlanInfoMap)
-
-			err := memoryMappedFile.Unmap()
-			if err != nil {
-				logger.Error("worker %v failed to conclude Transfer job with %v after processing chunkId %v", workerId, transferIdentifierStr, chunkId)
-				//fmt.Println("Worker", workerId, "failed to conclude TRANSFER job with", transferIdentifierStr, "after processing chunkId", chunkId)
-			}
 		}
 	}
 }
\ No newline at end of file
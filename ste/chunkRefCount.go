@@ -0,0 +1,46 @@
+package ste
+
+import (
+	"sync/atomic"
+)
+
+// chunkRefCount releases what the chunks of a transfer share, such as its memory mapped file, once no chunk uses it anymore
+// the prologue holds a reference while it schedules the chunks, and each chunk scheduled holds one until it is done,
+// whether it got transferred, failed, or was skipped since the transfer got cancelled
+type chunkRefCount struct {
+	count   int32
+	release func()
+}
+
+// newChunkRefCount returns a count holding the reference of the prologue, release may be nil if there is nothing to release
+func newChunkRefCount(release func()) *chunkRefCount {
+	return &chunkRefCount{count: 1, release: release}
+}
+
+// schedule schedules the chunk msg holding a reference, which its doTransfer must give back by calling done
+func (refCount *chunkRefCount) schedule(chunkMsg ChunkMsg, chunkChannel chan<- ChunkMsg) {
+	atomic.AddInt32(&refCount.count, 1)
+	if !scheduleChunkMsg(chunkMsg, chunkChannel) {
+		refCount.done()
+	}
+}
+
+// scheduleAsync schedules the chunk msg as schedule does, without holding the caller until the chunk channel has room
+// the chunk msg is counted in flight before it returns, so that its job part is not cleaned meanwhile
+func (refCount *chunkRefCount) scheduleAsync(chunkMsg ChunkMsg, chunkChannel chan<- ChunkMsg) {
+	atomic.AddInt32(&refCount.count, 1)
+	if !acquireChunkMsgInFlight(chunkMsg) {
+		refCount.done()
+		return
+	}
+	go func() {
+		chunkChannel <- chunkMsg
+	}()
+}
+
+// done gives back a reference, the last one releases what the chunks share
+func (refCount *chunkRefCount) done() {
+	if atomic.AddInt32(&refCount.count, -1) == 0 && refCount.release != nil {
+		refCount.release()
+	}
+}
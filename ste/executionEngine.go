@@ -1,13 +1,12 @@
 package ste
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"github.com/Azure/azure-storage-azcopy/common"
-	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
 	"github.com/edsrzf/mmap-go"
 	"os"
+	"path/filepath"
 	"time"
 )
 
@@ -16,8 +15,22 @@ func InitializeExecutionEngine(execEngineChannels *EEChannels) {
 	highTransfer := execEngineChannels.HighTransfer
 	suicideLine := execEngineChannels.SuicideChannel
 
-	for i := 1; i <= 5; i++ {
-		go engineWorker(i, highChunk, highTransfer, suicideLine)
+	// spawn new workers or ask the existing ones to commit suicide until the given number of workers is running
+	numOfWorkers := 0
+	lastWorkerId := 0
+	scaleWorkers := func(targetNumOfWorkers int) {
+		for ; numOfWorkers < targetNumOfWorkers; numOfWorkers++ {
+			lastWorkerId++
+			go engineWorker(lastWorkerId, highChunk, highTransfer, suicideLine)
+		}
+		for ; numOfWorkers > targetNumOfWorkers; numOfWorkers-- {
+			suicideLine <- SuicideJob(0)
+		}
+	}
+
+	scaleWorkers(common.DefaultNumOfEngineWorkers)
+	for targetNumOfWorkers := range execEngineChannels.WorkerCount {
+		scaleWorkers(targetNumOfWorkers)
 	}
 }
 
@@ -33,20 +46,22 @@ func engineWorker(workerId int, highPriorityChunkChannel chan ChunkMsg, highPrio
 			// priority 1: high priority chunk channel, do actual upload/download
 			select {
 			case chunkJobItem := <-highPriorityChunkChannel:
+				// chunks of a job which got cleaned meanwhile are dropped
+				jHandler, err := getJobPartInfoHandlerFromMap(chunkJobItem.jobId, chunkJobItem.partNumber, chunkJobItem.jPartPlanInfoMap)
+				if err != nil {
+					continue
+				}
+				// chunks of a paused job are parked until the job is unpaused, they stay in flight meanwhile
+				if jHandler.parkChunkMsgIfPaused(chunkJobItem) {
+					continue
+				}
 				chunkJobItem.doTransfer(workerId)
+				jHandler.releaseMsgInFlight()
 			default:
 				// priority 2: high priority transfer channel, schedule chunkMsgs
 				select {
 				case transferMsg := <-highPriorityTransferChannel:
-					logger := getLoggerFromJobPartPlanInfo(transferMsg.Id, transferMsg.PartNumber, transferMsg.JPartPlanInfoMap)
-					logger.Debug("Worker %d is processing TRANSFER job with jobId %s and partNum %d and transferId %d", workerId, transferMsg.Id, transferMsg.PartNumber, transferMsg.TransferIndex)
-					transferMsgDetail := getTransferMsgDetail(transferMsg.Id, transferMsg.PartNumber, transferMsg.TransferIndex, transferMsg.JPartPlanInfoMap)
-					prologueFunction := computePrologueFunc(transferMsgDetail.SourceType, transferMsgDetail.DestinationType)
-					if prologueFunction == nil{
-						logger.Error("Unrecognizable type of transfer with sourceLocationType as %d and destinationLocationType as %d", transferMsgDetail.SourceType, transferMsgDetail.DestinationType)
-						panic(errors.New(fmt.Sprintf("Unrecognizable type of transfer with sourceLocationType as %d and destinationLocationType as %d", transferMsgDetail.SourceType, transferMsgDetail.DestinationType)))
-					}
-					prologueFunction(transferMsgDetail, highPriorityChunkChannel)
+					processTransferMsg(workerId, transferMsg, highPriorityChunkChannel)
 				default:
 					// lower priorities should go here in the future
 					//fmt.Println("Worker", workerId, "is IDLE, sleeping for 0.01 sec zzzzzz")
@@ -57,18 +72,95 @@ func engineWorker(workerId int, highPriorityChunkChannel chan ChunkMsg, highPrio
 	}
 }
 
+// processTransferMsg runs the prologue of the transfer, which schedules its chunkMsgs
+// the transfer is in flight meanwhile, so that its job is not cleaned before the chunkMsgs are counted in flight
+func processTransferMsg(workerId int, transferMsg TransferMsg, chunkChannel chan ChunkMsg) {
+	// transfers of a job which got cleaned meanwhile are dropped
+	jHandler, err := getJobPartInfoHandlerFromMap(transferMsg.Id, transferMsg.PartNumber, transferMsg.JPartPlanInfoMap)
+	if err != nil {
+		return
+	}
+	// transfers of a paused job are parked until the job is unpaused
+	if jHandler.parkTransferMsgIfPaused(transferMsg) {
+		return
+	}
+	if !jHandler.acquireMsgInFlight() {
+		return
+	}
+	defer jHandler.releaseMsgInFlight()
+
+	logger := jHandler.Logger
+	logger.Debug("Worker %d is processing TRANSFER job with jobId %s and partNum %d and transferId %d", workerId, transferMsg.Id, transferMsg.PartNumber, transferMsg.TransferIndex)
+	transferMsgDetail := getTransferMsgDetail(transferMsg.Id, transferMsg.PartNumber, transferMsg.TransferIndex, transferMsg.JPartPlanInfoMap)
+	// a msg scheduled before the transfer got resumed is stale, the msg scheduled on resume sets the transfer up instead
+	if transferMsgDetail.TransferCtx != transferMsg.TransferCtx {
+		logger.Debug("Worker %d is dropping stale TRANSFER job with jobId %s and partNum %d and transferId %d", workerId, transferMsg.Id, transferMsg.PartNumber, transferMsg.TransferIndex)
+		return
+	}
+	// the transfers of a cancelled job are not set up anymore
+	if transferMsgDetail.TransferCtx.Err() != nil {
+		logger.Debug("Worker %d is skipping cancelled TRANSFER job with jobId %s and partNum %d and transferId %d", workerId, transferMsg.Id, transferMsg.PartNumber, transferMsg.TransferIndex)
+		return
+	}
+	prologueFunction := computePrologueFunc(transferMsgDetail.SourceType, transferMsgDetail.DestinationType, transferMsgDetail.BlobType)
+	if prologueFunction == nil {
+		logger.Error("Unrecognizable type of transfer with sourceLocationType as %d and destinationLocationType as %d", transferMsgDetail.SourceType, transferMsgDetail.DestinationType)
+		panic(errors.New(fmt.Sprintf("Unrecognizable type of transfer with sourceLocationType as %d and destinationLocationType as %d", transferMsgDetail.SourceType, transferMsgDetail.DestinationType)))
+	}
+	prologueFunction(transferMsgDetail, chunkChannel)
+}
+
 // the prologue function is generated based on the type of source and destination
-func computePrologueFunc(sourceLocationType, destinationLocationType common.LocationType) prologueFunc {
+// uploads are further distinguished by the type of blob the local files are uploaded as
+func computePrologueFunc(sourceLocationType, destinationLocationType common.LocationType, blobType common.BlobType) prologueFunc {
 	switch {
 	case sourceLocationType == common.Blob && destinationLocationType == common.Local: // download from Azure to local
 		return blobToLocal{}.prologue
+	case sourceLocationType == common.Local && destinationLocationType == common.Blob && blobType == common.PageBlob: // upload from local to Azure as page blob
+		return localToPageBlob{}.prologue
+	case sourceLocationType == common.Local && destinationLocationType == common.Blob && blobType == common.AppendBlob: // upload from local to Azure as append blob
+		return localToAppendBlob{}.prologue
 	case sourceLocationType == common.Local && destinationLocationType == common.Blob: // upload from local to Azure
 		return localToBlockBlob{}.prologue
+	case sourceLocationType == common.Local && destinationLocationType == common.File: // upload from local to Azure Files
+		return localToFile{}.prologue
+	case sourceLocationType == common.File && destinationLocationType == common.Local: // download from Azure Files to local
+		return fileToLocal{}.prologue
+	case sourceLocationType == common.S3 && destinationLocationType == common.Blob: // copy from S3 to Azure
+		return s3ToBlockBlob{}.prologue
+	case sourceLocationType == common.GCS && destinationLocationType == common.Blob: // copy from GCS to Azure, through its S3-compatible XML API
+		return s3ToBlockBlob{}.prologue
+	case sourceLocationType == common.Http && destinationLocationType == common.Blob: // upload from any http(s) url to Azure
+		return httpToBlockBlob{}.prologue
+	case sourceLocationType == common.Blob && destinationLocationType == common.Blob: // copy between blobs in Azure, server side
+		return blobToBlob{}.prologue
+	case sourceLocationType == common.Blob && destinationLocationType == common.Delete: // delete blobs in Azure
+		return blobDelete{}.prologue
+	case sourceLocationType == common.Local && destinationLocationType == common.Delete: // delete local files
+		return localDelete{}.prologue
 	default:
 		return nil
 	}
 }
 
+// scheduleChunkMsg puts the chunk msg into the chunk channel, counting it in flight until a worker is done with it
+// it is called while processing a msg of the same job part, which keeps the part from being cleaned meanwhile
+// returns false if the chunk msg was dropped since the job part got cleaned
+func scheduleChunkMsg(chunkMsg ChunkMsg, chunkChannel chan<- ChunkMsg) bool {
+	if !acquireChunkMsgInFlight(chunkMsg) {
+		return false
+	}
+	chunkChannel <- chunkMsg
+	return true
+}
+
+// acquireChunkMsgInFlight counts the chunk msg in flight for its job part
+// returns false if the job part got cleaned, in which case the chunk msg must not be scheduled
+func acquireChunkMsgInFlight(chunkMsg ChunkMsg) bool {
+	jHandler, err := getJobPartInfoHandlerFromMap(chunkMsg.jobId, chunkMsg.partNumber, chunkMsg.jPartPlanInfoMap)
+	return err == nil && jHandler.acquireMsgInFlight()
+}
+
 // for a given total size, compute how many chunks there are
 func computeNumOfChunks(totalSize int64, chunkSize int64) uint32 {
 	if totalSize%chunkSize == 0 {
@@ -79,44 +171,51 @@ func computeNumOfChunks(totalSize int64, chunkSize int64) uint32 {
 }
 
 // opens file with desired flags and return *os.File
-func openFile(filePath string, flags int) *os.File {
+// the directories of a file which gets created are created first, so that blobs in virtual directories can be downloaded
+// the error is returned rather than panicking, so that the worker can fail the transfer
+func openFile(filePath string, flags int) (*os.File, error) {
+	if flags&os.O_CREATE != 0 {
+		if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+			return nil, fmt.Errorf("error creating the directory of file: %s", err)
+		}
+	}
 	f, err := os.OpenFile(filePath, flags, 0644)
 	if err != nil {
-		panic(fmt.Sprintf("Error opening file: %s", err))
+		return nil, fmt.Errorf("error opening file: %s", err)
 	}
-	return f
+	return f, nil
 }
 
 // maps a *os.File into memory and return a byte slice (mmap.MMap)
-func mapFile(file *os.File) mmap.MMap {
+// the mapping outlives the file, which is closed
+func mapFile(file *os.File) (mmap.MMap, error) {
+	defer file.Close()
 	memoryMappedFile, err := mmap.Map(file, mmap.RDWR, 0)
 	if err != nil {
-		panic(fmt.Sprintf("Error mapping: %s", err))
+		return nil, fmt.Errorf("error mapping: %s", err)
 	}
-	return memoryMappedFile
+	return memoryMappedFile, nil
 }
 
 // create and memory map a file, given its path and length
-func createAndMemoryMapFile(destinationPath string, fileSize int64) mmap.MMap {
-	f := openFile(destinationPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC)
+func createAndMemoryMapFile(destinationPath string, fileSize int64) (mmap.MMap, error) {
+	f, err := openFile(destinationPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return nil, err
+	}
 	if truncateError := f.Truncate(fileSize); truncateError != nil {
-		panic(truncateError)
+		f.Close()
+		return nil, truncateError
 	}
 
 	return mapFile(f)
 }
 
 // open and memory map a file, given its path
-func openAndMemoryMapFile(destinationPath string) mmap.MMap {
-	f := openFile(destinationPath, os.O_RDWR)
-	return mapFile(f)
-}
-
-// make a HEAD request to get the blob size
-func getBlobSize(blobUrl azblob.BlobURL) int64 {
-	blobProperties, err := blobUrl.GetPropertiesAndMetadata(context.Background(), azblob.BlobAccessConditions{})
+func openAndMemoryMapFile(destinationPath string) (mmap.MMap, error) {
+	f, err := openFile(destinationPath, os.O_RDWR)
 	if err != nil {
-		panic("Cannot get blob size")
+		return nil, err
 	}
-	return blobProperties.ContentLength()
+	return mapFile(f)
 }
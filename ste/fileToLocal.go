@@ -0,0 +1,136 @@
+package ste
+
+import (
+	"context"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/edsrzf/mmap-go"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+type fileToLocal struct{}
+
+// this function performs the setup for each transfer and schedules the corresponding chunkMsgs into the chunkChannel
+// the ranges of the file in the share are downloaded into the local file, whose directories get created first
+func (fileToLocal fileToLocal) prologue(transfer TransferMsgDetail, chunkChannel chan<- ChunkMsg) {
+	logger := getLoggerFromJobPartPlanInfo(transfer.JobId, transfer.PartNumber, transfer.JobHandlerMap)
+	transferIdentifierStr := fmt.Sprintf("jobId %s and partNum %d and transferId %d", transfer.JobId, transfer.PartNumber, transfer.TransferId)
+
+	// step 1: get file size, the chunks downloaded before the job got resumed are only reused if the file did not change meanwhile
+	u, _ := url.Parse(transfer.Source)
+	fileUrl := common.NewFileURL(*u)
+	fileSize, lastModified, err := fileUrl.GetProperties(transfer.TransferCtx)
+	if err == nil {
+		err = restartTransferIfSourceChanged(transfer, fileSize, lastModified)
+	}
+	if err != nil {
+		logger.Error("failed to get the properties of the source of Transfer job with %s due to error %s", transferIdentifierStr, err.Error())
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+		return
+	}
+
+	// step 2: find the chunks which were already downloaded before the job got resumed
+	numOfChunks := computeNumOfChunks(fileSize, int64(transfer.ChunkSize))
+	chunksDownloaded, numOfChunksDownloaded := getCompletedChunks(transfer, numOfChunks)
+
+	// step 3: create the directories of the local file
+	err = os.MkdirAll(filepath.Dir(transfer.Destination), os.ModePerm)
+	if err != nil {
+		logger.Error("failed to create the directory of the destination of Transfer job with %s due to error %s", transferIdentifierStr, err.Error())
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+		return
+	}
+
+	// step 4: an empty file only needs the local file to be created
+	if fileSize == 0 {
+		destinationFile, err := openFile(transfer.Destination, os.O_RDWR|os.O_CREATE|os.O_TRUNC)
+		if err != nil {
+			logger.Error("failed to create the destination of Transfer job with %s due to error %s", transferIdentifierStr, err.Error())
+			updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+			return
+		}
+		destinationFile.Close()
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusComplete, transfer.JobHandlerMap)
+		return
+	}
+
+	// step 5: prep local file before download starts
+	memoryMappedFile, refCount, ok := mapDestinationFile(transfer, fileSize, chunksDownloaded, &numOfChunksDownloaded)
+	if !ok {
+		return
+	}
+
+	// step 6: conclude the transfer right away if every chunk was downloaded already
+	if numOfChunksDownloaded == numOfChunks {
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusComplete, transfer.JobHandlerMap)
+		refCount.done()
+		return
+	}
+
+	// step 7: go through the file range and schedule download chunk jobs/msgs for the chunks not downloaded yet
+	scheduleChunks(transfer, chunkChannel, fileSize, chunksDownloaded, refCount,
+		func(chunkId int32, startIndex int64, chunkSize int64) chunkFunc {
+			return generateGetRangeFunc(
+				transfer.JobId,
+				transfer.PartNumber,
+				transfer.TransferId,
+				chunkId,
+				numOfChunks,
+				chunkSize,
+				startIndex,
+				fileUrl,
+				memoryMappedFile,
+				refCount,
+				transfer.TransferCtx,
+				transfer.TransferCancelFunc,
+				&numOfChunksDownloaded,
+				transfer.JobHandlerMap)
+		})
+}
+
+// this generates a function which performs the downloading of a single range of the file
+func generateGetRangeFunc(jobId common.JobID, partNum common.PartNumber, transferId uint32, chunkId int32, totalNumOfChunks uint32, chunkSize int64, startIndex int64,
+	fileUrl common.FileURL, memoryMappedFile mmap.MMap, refCount *chunkRefCount, ctx context.Context, cancelTransfer func(), progressCount *uint32, jPartPlanInfoMap *JobPartPlanInfoMap) chunkFunc {
+	return func(workerId int) {
+		logger := getLoggerFromJobPartPlanInfo(jobId, partNum, jPartPlanInfoMap)
+		transferIdentifierStr := fmt.Sprintf("jobId %s and partNum %d and transferId %d", jobId, partNum, transferId)
+		defer refCount.done()
+
+		if isChunkCancelled(ctx, workerId, chunkId, transferIdentifierStr, logger) {
+			return
+		}
+
+		// step 1: get the range and write it into the memory mapped file directly
+		startTime := time.Now()
+		body, err := fileUrl.GetRange(ctx, startIndex, chunkSize)
+		bytesRead := 0
+		if err == nil {
+			bytesRead, err = io.ReadFull(body, memoryMappedFile[startIndex:startIndex+chunkSize])
+			body.Close()
+		}
+		if int64(bytesRead) != chunkSize || err != nil {
+			// cancel entire transfer because this chunk has failed
+			cancelTransfer()
+			logger.Debug("worker %d is canceling Chunk job with %s and chunkId %d because startIndex of %d has failed", workerId, transferIdentifierStr, chunkId, startIndex)
+			updateChunkInfo(jobId, partNum, transferId, uint16(chunkId), [128 / 8]byte{}, ChunkTransferStatusFailed, jPartPlanInfoMap)
+			updateTransferStatus(jobId, partNum, transferId, common.TransferStatusFailed, jPartPlanInfoMap)
+			return
+		}
+
+		updateChunkInfo(jobId, partNum, transferId, uint16(chunkId), [128 / 8]byte{}, ChunkTransferStatusComplete, jPartPlanInfoMap)
+		recordChunkLatency(jobId, partNum, time.Since(startTime), jPartPlanInfoMap)
+		updateThroughputCounter(chunkSize)
+
+		// step 2: check if this is the last chunk
+		if atomic.AddUint32(progressCount, 1) == totalNumOfChunks {
+			// step 3: this is the last chunk, perform EPILOGUE
+			logger.Debug("worker %d is concluding download Transfer job with %s after processing chunkId %d", workerId, transferIdentifierStr, chunkId)
+			updateTransferStatus(jobId, partNum, transferId, common.TransferStatusComplete, jPartPlanInfoMap)
+		}
+	}
+}
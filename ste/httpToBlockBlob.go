@@ -0,0 +1,147 @@
+package ste
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"time"
+)
+
+type httpToBlockBlob struct{}
+
+// this function performs the setup for each transfer and schedules the corresponding chunkMsgs into the chunkChannel
+// each chunk gets a byte range of the source url and uploads it as a block of the destination blob
+// a server which does not serve ranges is read as a single stream instead, uploading its chunks one after the other
+func (httpToBlockBlob httpToBlockBlob) prologue(transfer TransferMsgDetail, chunkChannel chan<- ChunkMsg) {
+	logger := getLoggerFromJobPartPlanInfo(transfer.JobId, transfer.PartNumber, transfer.JobHandlerMap)
+	transferIdentifierStr := fmt.Sprintf("jobId %s and partNum %d and transferId %d", transfer.JobId, transfer.PartNumber, transfer.TransferId)
+
+	// step 1: create pipeline for the destination blob
+	blobUrl := newTransferBlobURL(transfer.Destination)
+
+	// step 2: get the size of the source, which the server must still tell
+	// the blocks uploaded before the job got resumed are only reused if the source did not change meanwhile
+	sourceUrl, _ := url.Parse(transfer.Source)
+	sourceProperties, err := common.GetHttpSourceProperties(transfer.TransferCtx, *sourceUrl)
+	if err == nil && sourceProperties.Size < 0 {
+		err = errors.New("the server does not tell the size of the source anymore")
+	}
+	if err == nil {
+		err = restartTransferIfSourceChanged(transfer, sourceProperties.Size, sourceProperties.LastModified)
+	}
+	if err != nil {
+		logger.Error("failed to get the properties of the source of Transfer job with %s due to error %s", transferIdentifierStr, err.Error())
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+		return
+	}
+	sourceSize := sourceProperties.Size
+	headers := azblob.BlobHTTPHeaders{ContentType: sourceProperties.ContentType}
+
+	// step 3: if the server does not serve ranges, the whole source is streamed by a single chunk job
+	if !sourceProperties.AcceptsRanges {
+		scheduleChunkMsg(ChunkMsg{
+			jobId:            transfer.JobId,
+			partNumber:       transfer.PartNumber,
+			jPartPlanInfoMap: transfer.JobHandlerMap,
+			doTransfer:       generateHttpStreamToBlocksFunc(transfer, *sourceUrl, sourceSize, blobUrl, headers),
+		}, chunkChannel)
+		return
+	}
+
+	// step 4: upload each range of the source which is not uploaded yet as a block
+	scheduleBlockUploads(transfer, chunkChannel, blobUrl, sourceSize, headers, azblob.Metadata{},
+		func() (chunkReader, func(), error) {
+			readChunk := func(ctx context.Context, startIndex int64, chunkSize int64) (io.ReadSeeker, error) {
+				body, err := common.GetHttpSourceRange(ctx, *sourceUrl, startIndex, chunkSize)
+				if err != nil {
+					return nil, err
+				}
+				defer body.Close()
+				chunkData := make([]byte, chunkSize)
+				_, err = io.ReadFull(body, chunkData)
+				return bytes.NewReader(chunkData), err
+			}
+			return readChunk, nil, nil
+		})
+}
+
+// this generates a function which reads the whole source in a single request and uploads it chunk by chunk
+// chunks uploaded before the job got resumed are read past, since the stream cannot start in the middle of the source
+func generateHttpStreamToBlocksFunc(transfer TransferMsgDetail, sourceUrl url.URL, sourceSize int64, blobURL azblob.BlobURL, headers azblob.BlobHTTPHeaders) chunkFunc {
+	return func(workerId int) {
+		logger := getLoggerFromJobPartPlanInfo(transfer.JobId, transfer.PartNumber, transfer.JobHandlerMap)
+		transferIdentifierStr := fmt.Sprintf("jobId %s and partNum %d and transferId %d", transfer.JobId, transfer.PartNumber, transfer.TransferId)
+		ctx := transfer.TransferCtx
+
+		if ctx.Err() != nil {
+			logger.Debug("worker %d is skipping stream job with %s since the transfer was cancelled", workerId, transferIdentifierStr)
+			return
+		}
+
+		failTransfer := func(chunkId int, blockId [128 / 8]byte, err error) {
+			transfer.TransferCancelFunc()
+			logger.Debug("worker %d is canceling stream job with %s at chunkId %d due to error %s", workerId, transferIdentifierStr, chunkId, err.Error())
+			updateChunkInfo(transfer.JobId, transfer.PartNumber, transfer.TransferId, uint16(chunkId), blockId, ChunkTransferStatusFailed, transfer.JobHandlerMap)
+			updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+		}
+
+		// step 1: open the stream of the whole source
+		body, err := common.GetHttpSource(ctx, sourceUrl)
+		if err != nil {
+			failTransfer(0, [128 / 8]byte{}, err)
+			return
+		}
+		defer body.Close()
+
+		// step 2: go through the stream chunk by chunk, uploading each chunk which is not uploaded yet as a block
+		chunkSize := int64(transfer.ChunkSize)
+		numOfBlocks := computeNumOfChunks(sourceSize, chunkSize)
+		blockIds := make([]string, numOfBlocks)
+		chunkData := make([]byte, chunkSize)
+		for chunkId := 0; chunkId < int(numOfBlocks); chunkId++ {
+			startIndex := int64(chunkId) * chunkSize
+			adjustedChunkSize := chunkSize
+			if startIndex+chunkSize > sourceSize {
+				adjustedChunkSize = sourceSize - startIndex
+			}
+
+			chunkStatus, blockId := getChunkStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, uint16(chunkId), transfer.JobHandlerMap)
+			if chunkStatus == ChunkTransferStatusComplete {
+				blockIds[chunkId] = encodeBlockId(blockId)
+				_, err = io.CopyN(ioutil.Discard, body, adjustedChunkSize)
+				if err != nil {
+					failTransfer(chunkId, blockId, err)
+					return
+				}
+				continue
+			}
+
+			blockId = newBlockId()
+			blockIds[chunkId] = encodeBlockId(blockId)
+			startTime := time.Now()
+			_, err = io.ReadFull(body, chunkData[:adjustedChunkSize])
+			if err == nil {
+				_, err = blobURL.ToBlockBlobURL().PutBlock(ctx, blockIds[chunkId], bytes.NewReader(chunkData[:adjustedChunkSize]), azblob.LeaseAccessConditions{})
+			}
+			if err != nil {
+				failTransfer(chunkId, blockId, err)
+				return
+			}
+
+			// the block ID is persisted, so that a resumed job does not upload this chunk again
+			updateChunkInfo(transfer.JobId, transfer.PartNumber, transfer.TransferId, uint16(chunkId), blockId, ChunkTransferStatusComplete, transfer.JobHandlerMap)
+			recordChunkLatency(transfer.JobId, transfer.PartNumber, time.Since(startTime), transfer.JobHandlerMap)
+			updateThroughputCounter(adjustedChunkSize)
+		}
+
+		// step 3: the whole source is uploaded, perform EPILOGUE
+		logger.Debug("worker %d is concluding stream Transfer job with %s with blocklist %s", workerId, transferIdentifierStr, blockIds)
+		commitBlockList(transfer.JobId, transfer.PartNumber, transfer.TransferId, blobURL, ctx, blockIds, headers, azblob.Metadata{}, transfer.JobHandlerMap)
+	}
+}
@@ -0,0 +1,169 @@
+package ste
+
+import (
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"os"
+	"sort"
+	"time"
+)
+
+// getJobCompletionTime returns the time at which the last transfer of given job parts completed
+// isComplete is false if the job still has active transfers or if its final part has not been ordered yet
+// a cancelled job is complete once none of its transfers are active anymore
+func getJobCompletionTime(jPartMap map[common.PartNumber]*JobPartPlanInfo) (completionTime time.Time, isComplete bool) {
+	completeJobOrdered := false
+	var lastCompletionTime uint64 = 0
+	for _, jHandler := range jPartMap {
+		jPartPlan := jHandler.getJobPartPlanPointer()
+		completeJobOrdered = completeJobOrdered || jPartPlan.IsFinalPart
+		// a part without transfers completed once it was ordered, i.e. when its job part plan file was written
+		if jPartPlan.NumTransfers == 0 {
+			if fileInfo, err := os.Stat(jHandler.fileName); err == nil && uint64(fileInfo.ModTime().UnixNano()) > lastCompletionTime {
+				lastCompletionTime = uint64(fileInfo.ModTime().UnixNano())
+			}
+		}
+		for index := uint32(0); index < jPartPlan.NumTransfers; index++ {
+			transferHeader := jHandler.Transfer(index)
+			if transferHeader.Status == common.TransferStatusActive {
+				return time.Time{}, false
+			}
+			if transferHeader.Status == common.TransferStatusCancelled {
+				completeJobOrdered = true
+			}
+			if transferHeader.CompletionTime > lastCompletionTime {
+				lastCompletionTime = transferHeader.CompletionTime
+			}
+		}
+	}
+	return time.Unix(0, int64(lastCompletionTime)), completeJobOrdered
+}
+
+// getJobTTLAfterCompletion returns the longest time to live after completion among given job parts
+func getJobTTLAfterCompletion(jPartMap map[common.PartNumber]*JobPartPlanInfo) time.Duration {
+	var ttl uint32 = 0
+	for _, jHandler := range jPartMap {
+		if partTTL := jHandler.getJobPartPlanPointer().TTLAfterCompletion; partTTL > ttl {
+			ttl = partTTL
+		}
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+// acquireMsgInFlight counts a msg of the job part in flight
+// returns false if the part got cleaned already, in which case the msg is dropped
+func (job *JobPartPlanInfo) acquireMsgInFlight() bool {
+	job.inFlightLock.Lock()
+	defer job.inFlightLock.Unlock()
+	if job.isCleaned {
+		return false
+	}
+	job.numMsgsInFlight++
+	return true
+}
+
+// releaseMsgInFlight counts a msg of the job part out of flight once a worker is done with it
+func (job *JobPartPlanInfo) releaseMsgInFlight() {
+	job.inFlightLock.Lock()
+	defer job.inFlightLock.Unlock()
+	job.numMsgsInFlight--
+}
+
+// markJobPartsCleaned marks every given job part cleaned, provided none of them has msgs in flight
+// once marked, no msg of the parts can get in flight anymore, so that their job part plans can be unmapped safely
+// returns false if a part still has msgs in flight, in which case no part is marked
+func markJobPartsCleaned(jPartMap map[common.PartNumber]*JobPartPlanInfo) bool {
+	// the parts are locked in the order of their part numbers, so that concurrent cleanings of a job do not deadlock
+	var partNumbers []int
+	for partNo := range jPartMap {
+		partNumbers = append(partNumbers, int(partNo))
+	}
+	sort.Ints(partNumbers)
+	for _, partNo := range partNumbers {
+		jHandler := jPartMap[common.PartNumber(partNo)]
+		jHandler.inFlightLock.Lock()
+		defer jHandler.inFlightLock.Unlock()
+	}
+
+	for _, jHandler := range jPartMap {
+		if jHandler.isCleaned || jHandler.numMsgsInFlight > 0 {
+			return false
+		}
+	}
+	for _, jHandler := range jPartMap {
+		jHandler.isCleaned = true
+	}
+	return true
+}
+
+// cleanJob removes an existing job from the transfer engine
+// it unmaps the job part plan files of the job and deletes them, closes the log file of the job and deletes it,
+// and removes the entries of the job from JobPartPlanInfoMap and JobToLoggerMap
+// a job with msgs still in flight, such as the chunks of cancelled transfers which are not drained yet, is not removed
+// returns whether the job was removed
+func cleanJob(jobId common.JobID, jPartPlanInfoMap *JobPartPlanInfoMap, jobToLoggerMap *JobToLoggerMap) bool {
+	jPartMap, ok := jPartPlanInfoMap.LoadPartPlanMapforJob(jobId)
+	if !ok || !markJobPartsCleaned(jPartMap) {
+		return false
+	}
+	jPartPlanInfoMap.DeleteJobInfoForJobId(jobId)
+
+	for partNo, jHandler := range jPartMap {
+		_, cancel := jHandler.partContext()
+		cancel()
+		jHandler.shutDownHandler()
+		jHandler.memMap = nil
+		err := os.Remove(jHandler.fileName)
+		if err != nil && !os.IsNotExist(err) {
+			jHandler.Logger.Error("failed to delete the job part plan file of part number %d of job %s due to error %s", partNo, jobId, err.Error())
+		}
+	}
+
+	logger := jobToLoggerMap.LoadLoggerForJob(jobId)
+	if logger == nil {
+		return true
+	}
+	jobToLoggerMap.DeleteLoggerForJob(jobId)
+	logger.LogFile.Close()
+	err := os.Remove(logger.LogFileName)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Println(fmt.Sprintf("failed to delete the log file of job %s due to error %s", jobId, err.Error()))
+	}
+	return true
+}
+
+// cleanExpiredJobs removes the completed jobs which expired from the transfer engine
+// a job expires once the given duration has passed since its completion, or once its time to live has passed if no duration is given
+// returns the JobIds of the jobs removed
+func cleanExpiredJobs(olderThan *time.Duration, jPartPlanInfoMap *JobPartPlanInfoMap, jobToLoggerMap *JobToLoggerMap) []common.JobID {
+	var cleanedJobIds []common.JobID
+	for _, jobId := range jPartPlanInfoMap.LoadExistingJobIds() {
+		jPartMap, ok := jPartPlanInfoMap.LoadPartPlanMapforJob(jobId)
+		if !ok {
+			continue
+		}
+		completionTime, isComplete := getJobCompletionTime(jPartMap)
+		if !isComplete {
+			continue
+		}
+		expiresAfter := getJobTTLAfterCompletion(jPartMap)
+		if olderThan != nil {
+			expiresAfter = *olderThan
+		}
+		if time.Since(completionTime) < expiresAfter {
+			continue
+		}
+		// a job whose msgs are still in flight is cleaned by a later sweep
+		if cleanJob(jobId, jPartPlanInfoMap, jobToLoggerMap) {
+			cleanedJobIds = append(cleanedJobIds, jobId)
+		}
+	}
+	return cleanedJobIds
+}
+
+// sweepExpiredJobs periodically removes the completed jobs whose time to live has passed
+func sweepExpiredJobs(sweepInterval time.Duration, jPartPlanInfoMap *JobPartPlanInfoMap, jobToLoggerMap *JobToLoggerMap) {
+	for range time.Tick(sweepInterval) {
+		cleanExpiredJobs(nil, jPartPlanInfoMap, jobToLoggerMap)
+	}
+}
@@ -0,0 +1,62 @@
+package ste
+
+import (
+	"context"
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// partContext returns the context of the job part and the func cancelling it
+// the context of a part is replaced when the part gets resumed after it was cancelled, so it is read under ctxLock
+func (job *JobPartPlanInfo) partContext() (context.Context, context.CancelFunc) {
+	job.ctxLock.RLock()
+	defer job.ctxLock.RUnlock()
+	return job.ctx, job.cancel
+}
+
+// transferContext returns the context of the transfer at given index and the func cancelling it
+func (job *JobPartPlanInfo) transferContext(index uint32) (context.Context, context.CancelFunc) {
+	job.ctxLock.RLock()
+	defer job.ctxLock.RUnlock()
+	return job.TrasnferInfo[index].ctx, job.TrasnferInfo[index].cancel
+}
+
+// markScheduled records that the transfers of the job part were scheduled by the current instance of transfer engine
+func (job *JobPartPlanInfo) markScheduled() {
+	job.ctxLock.Lock()
+	defer job.ctxLock.Unlock()
+	job.isScheduled = true
+}
+
+// prepareResume gives a new context to the job part if it got cancelled, and to each of its transfers left to resume
+/*
+	* transfers which are already complete are not resumed
+	* transfers still in flight in the current instance of transfer engine are not resumed
+	* every other transfer gets a new context and is marked active
+ */
+// returns the indices of the transfers to schedule again, which must be scheduled once ctxLock is released
+func (job *JobPartPlanInfo) prepareResume() []uint32 {
+	job.ctxLock.Lock()
+	defer job.ctxLock.Unlock()
+	if job.ctx.Err() != nil {
+		job.ctx, job.cancel = context.WithCancel(steContext)
+	}
+	jPartPlan := job.getJobPartPlanPointer()
+	jPartPlan.IsCancelled = false
+	var indices []uint32
+	for index := uint32(0); index < jPartPlan.NumTransfers; index++ {
+		transferHeader := job.Transfer(index)
+		if transferHeader.Status == common.TransferStatusComplete {
+			continue
+		}
+		transferInfo := &job.TrasnferInfo[index]
+		if job.isScheduled && transferHeader.Status == common.TransferStatusActive && transferInfo.ctx.Err() == nil {
+			continue
+		}
+		transferInfo.ctx, transferInfo.cancel = context.WithCancel(job.ctx)
+		transferHeader.Status = common.TransferStatusActive
+		transferHeader.CompletionTime = 0
+		indices = append(indices, index)
+	}
+	job.isScheduled = true
+	return indices
+}
@@ -0,0 +1,20 @@
+package ste
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// getSourceCredentials returns the credentials the sources of the job part are read with
+// the credentials come with the job part order, or with the request resuming the job, and are never written to the job part plan
+func (job *JobPartPlanInfo) getSourceCredentials() common.S3Credentials {
+	job.credentialsLock.RLock()
+	defer job.credentialsLock.RUnlock()
+	return job.sourceCredentials
+}
+
+// setSourceCredentials replaces the credentials the sources of the job part are read with
+func (job *JobPartPlanInfo) setSourceCredentials(credentials common.S3Credentials) {
+	job.credentialsLock.Lock()
+	defer job.credentialsLock.Unlock()
+	job.sourceCredentials = credentials
+}
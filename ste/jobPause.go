@@ -0,0 +1,55 @@
+package ste
+
+// pause marks the job part as paused
+// workers park the transfer and chunk msgs of a paused job part instead of processing them
+func (job *JobPartPlanInfo) pause() {
+	job.pauseLock.Lock()
+	defer job.pauseLock.Unlock()
+	job.isPaused = true
+}
+
+// unpause marks the job part as not paused anymore
+// returns the transfer and chunk msgs parked while the job part was paused, which need to be scheduled again
+func (job *JobPartPlanInfo) unpause() ([]TransferMsg, []ChunkMsg) {
+	job.pauseLock.Lock()
+	defer job.pauseLock.Unlock()
+	job.isPaused = false
+	parkedTransferMsgs, parkedChunkMsgs := job.parkedTransferMsgs, job.parkedChunkMsgs
+	job.parkedTransferMsgs, job.parkedChunkMsgs = nil, nil
+	return parkedTransferMsgs, parkedChunkMsgs
+}
+
+// takeParkedChunkMsgs returns the chunk msgs parked while the job part was paused, leaving the part paused
+// the chunk msgs of a part cancelled while paused need to be scheduled again to drain
+func (job *JobPartPlanInfo) takeParkedChunkMsgs() []ChunkMsg {
+	job.pauseLock.Lock()
+	defer job.pauseLock.Unlock()
+	parkedChunkMsgs := job.parkedChunkMsgs
+	job.parkedChunkMsgs = nil
+	return parkedChunkMsgs
+}
+
+// parkTransferMsgIfPaused keeps the given transfer msg aside if the job part is paused
+// returns true if the transfer msg was parked
+func (job *JobPartPlanInfo) parkTransferMsgIfPaused(transferMsg TransferMsg) bool {
+	job.pauseLock.Lock()
+	defer job.pauseLock.Unlock()
+	if !job.isPaused {
+		return false
+	}
+	job.parkedTransferMsgs = append(job.parkedTransferMsgs, transferMsg)
+	return true
+}
+
+// parkChunkMsgIfPaused keeps the given chunk msg aside if the job part is paused
+// chunk msgs of a cancelled job part are not parked, so that they drain and release what their transfers hold
+// returns true if the chunk msg was parked
+func (job *JobPartPlanInfo) parkChunkMsgIfPaused(chunkMsg ChunkMsg) bool {
+	job.pauseLock.Lock()
+	defer job.pauseLock.Unlock()
+	if ctx, _ := job.partContext(); !job.isPaused || ctx.Err() != nil {
+		return false
+	}
+	job.parkedChunkMsgs = append(job.parkedChunkMsgs, chunkMsg)
+	return true
+}
@@ -0,0 +1,63 @@
+package ste
+
+import (
+	"math"
+	"time"
+)
+
+// numLatencyBuckets is the number of buckets of a latencyHistogram, the last bucket holds the latencies of about an hour and more
+const numLatencyBuckets = 128
+
+// latencyHistogram counts latencies in buckets whose bounds grow by a quarter power of two, starting at a microsecond
+// its size does not grow with the number of latencies, and the percentiles it gives are at most a fifth above the exact ones
+type latencyHistogram struct {
+	counts [numLatencyBuckets]uint32
+	total  uint32
+}
+
+// latencyBucket returns the index of the bucket counting the given latency
+func latencyBucket(latency time.Duration) int {
+	microseconds := float64(latency) / float64(time.Microsecond)
+	if microseconds <= 1 {
+		return 0
+	}
+	bucket := int(math.Ceil(4 * math.Log2(microseconds)))
+	if bucket >= numLatencyBuckets {
+		bucket = numLatencyBuckets - 1
+	}
+	return bucket
+}
+
+// add counts the given latency
+func (histogram *latencyHistogram) add(latency time.Duration) {
+	histogram.counts[latencyBucket(latency)] += 1
+	histogram.total += 1
+}
+
+// merge adds the latencies counted by the other histogram
+func (histogram *latencyHistogram) merge(other *latencyHistogram) {
+	for bucket, count := range other.counts {
+		histogram.counts[bucket] += count
+	}
+	histogram.total += other.total
+}
+
+// percentileInMilliseconds returns the upper bound of the bucket holding the given percentile of the latencies in milliseconds
+func (histogram *latencyHistogram) percentileInMilliseconds(percentile int) float64 {
+	if histogram.total == 0 {
+		return 0
+	}
+	// rank is the position of the percentile among the sorted latencies, starting at 1
+	rank := (uint64(histogram.total)*uint64(percentile) + 99) / 100
+	if rank == 0 {
+		rank = 1
+	}
+	cumulativeCount := uint64(0)
+	for bucket, count := range histogram.counts {
+		cumulativeCount += uint64(count)
+		if cumulativeCount >= rank {
+			return math.Pow(2, float64(bucket)/4) * float64(time.Microsecond) / float64(time.Millisecond)
+		}
+	}
+	return math.Pow(2, float64(numLatencyBuckets-1)/4) * float64(time.Microsecond) / float64(time.Millisecond)
+}
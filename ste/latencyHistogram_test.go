@@ -0,0 +1,77 @@
+package ste
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogramPercentileInMilliseconds(t *testing.T) {
+	// latencies repeats each latency the given number of times
+	latencies := func(latency time.Duration, count int) []time.Duration {
+		repeated := make([]time.Duration, count)
+		for i := range repeated {
+			repeated[i] = latency
+		}
+		return repeated
+	}
+	// 90 latencies of a millisecond followed by 10 of a second
+	mixedLatencies := append(latencies(time.Millisecond, 90), latencies(time.Second, 10)...)
+
+	testCases := []struct {
+		name       string
+		latencies  []time.Duration
+		percentile int
+		expected   float64
+	}{
+		{"no latencies", nil, 50, 0},
+		// a millisecond falls in the bucket ending at 2^10 microseconds
+		{"single latency", latencies(time.Millisecond, 1), 50, 1.024},
+		{"latency of a microsecond", latencies(time.Microsecond, 1), 50, 0.001},
+		{"latency below a microsecond", latencies(time.Nanosecond, 1), 50, 0.001},
+		// the last bucket holds every latency above its lower bound
+		{"latency of two hours", latencies(2*time.Hour, 1), 50, math.Pow(2, float64(numLatencyBuckets-1)/4) / 1000},
+		// the percentile is the latency of the rank which is the percentile of the count, rounded up
+		{"zeroth percentile", mixedLatencies, 0, 1.024},
+		{"median", mixedLatencies, 50, 1.024},
+		{"last percentile of the lower latencies", mixedLatencies, 90, 1.024},
+		{"first percentile of the higher latencies", mixedLatencies, 91, 1048.576},
+		{"hundredth percentile", mixedLatencies, 100, 1048.576},
+	}
+	for _, testCase := range testCases {
+		histogram := latencyHistogram{}
+		for _, latency := range testCase.latencies {
+			histogram.add(latency)
+		}
+		if percentile := histogram.percentileInMilliseconds(testCase.percentile); math.Abs(percentile-testCase.expected) > 1e-9 {
+			t.Errorf("%s: got percentile %d of %vms, expected %vms", testCase.name, testCase.percentile, percentile, testCase.expected)
+		}
+	}
+}
+
+// the percentile given by the histogram is at most a fifth above the exact one
+func TestLatencyHistogramPercentileBound(t *testing.T) {
+	for latency := time.Microsecond; latency < time.Hour; latency = latency*3/2 + time.Nanosecond {
+		histogram := latencyHistogram{}
+		histogram.add(latency)
+		exact := float64(latency) / float64(time.Millisecond)
+		if percentile := histogram.percentileInMilliseconds(50); percentile < exact || percentile > exact*1.2 {
+			t.Errorf("got percentile of %vms for a latency of %vms", percentile, exact)
+		}
+	}
+}
+
+// merging histograms gives the percentiles of the latencies of both
+func TestLatencyHistogramMerge(t *testing.T) {
+	histogram, other := latencyHistogram{}, latencyHistogram{}
+	histogram.add(time.Millisecond)
+	other.add(time.Second)
+	other.add(time.Second)
+	histogram.merge(&other)
+	if histogram.total != 3 {
+		t.Fatalf("got a total of %d latencies, expected 3", histogram.total)
+	}
+	if percentile := histogram.percentileInMilliseconds(50); percentile != 1048.576 {
+		t.Errorf("got median of %vms, expected 1048.576ms", percentile)
+	}
+}
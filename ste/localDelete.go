@@ -0,0 +1,51 @@
+package ste
+
+import (
+	"context"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"os"
+)
+
+type localDelete struct{}
+
+// this function schedules a single chunkMsg which deletes the source file of the transfer
+func (localDelete localDelete) prologue(transfer TransferMsgDetail, chunkChannel chan<- ChunkMsg) {
+	scheduleChunkMsg(ChunkMsg{
+		jobId:            transfer.JobId,
+		partNumber:       transfer.PartNumber,
+		jPartPlanInfoMap: transfer.JobHandlerMap,
+		doTransfer:       generateLocalDeleteFunc(
+			transfer.JobId,
+			transfer.PartNumber,
+			transfer.TransferId,
+			transfer.Source,
+			transfer.TransferCtx,
+			transfer.JobHandlerMap),
+	}, chunkChannel)
+}
+
+// this generates a function which deletes a local file
+func generateLocalDeleteFunc(jobId common.JobID, partNum common.PartNumber, transferId uint32, filePath string, ctx context.Context, jPartPlanInfoMap *JobPartPlanInfoMap) chunkFunc {
+	return func(workerId int) {
+		logger := getLoggerFromJobPartPlanInfo(jobId, partNum, jPartPlanInfoMap)
+		transferIdentifierStr := fmt.Sprintf("jobId %s and partNum %d and transferId %d", jobId, partNum, transferId)
+
+		// the deletion of a cancelled transfer is not performed anymore
+		if ctx.Err() != nil {
+			logger.Debug("worker %d is skipping delete Transfer job with %s since the transfer was cancelled", workerId, transferIdentifierStr)
+			return
+		}
+
+		// a file which does not exist anymore has nothing left to delete
+		err := os.Remove(filePath)
+		if err != nil && !os.IsNotExist(err) {
+			logger.Error("worker %d failed to delete the file of transfer job with %s due to error %s", workerId, transferIdentifierStr, err.Error())
+			updateTransferStatus(jobId, partNum, transferId, common.TransferStatusFailed, jPartPlanInfoMap)
+			return
+		}
+
+		logger.Debug("worker %d is concluding delete Transfer job with %s", workerId, transferIdentifierStr)
+		updateTransferStatus(jobId, partNum, transferId, common.TransferStatusComplete, jPartPlanInfoMap)
+	}
+}
@@ -0,0 +1,174 @@
+package ste
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
+	"github.com/edsrzf/mmap-go"
+	"net/http"
+	"os"
+	"time"
+)
+
+type localToAppendBlob struct{}
+
+// this function performs the setup for each transfer and schedules the chunkMsg of its first chunk into the chunkChannel
+// blocks are appended in order, so each chunk schedules the next one once it is appended and only one chunk is in flight
+func (localToAppendBlob localToAppendBlob) prologue(transfer TransferMsgDetail, chunkChannel chan<- ChunkMsg) {
+	logger := getLoggerFromJobPartPlanInfo(transfer.JobId, transfer.PartNumber, transfer.JobHandlerMap)
+	transferIdentifierStr := fmt.Sprintf("jobId %s and partNum %d and transferId %d", transfer.JobId, transfer.PartNumber, transfer.TransferId)
+
+	// step 1: create pipeline for the destination blob
+	appendBlobUrl := newTransferBlobURL(transfer.Destination).ToAppendBlobURL()
+
+	// step 2: get the file size, the chunks appended before the job got resumed are only reused if the file did not change meanwhile
+	fi, err := os.Stat(transfer.Source)
+	if err == nil {
+		err = restartTransferIfSourceChanged(transfer, fi.Size(), fi.ModTime())
+	}
+	if err != nil {
+		logger.Error("failed to get the properties of the source of Transfer job with %s due to error %s", transferIdentifierStr, err.Error())
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+		return
+	}
+	blobSize := fi.Size()
+
+	// step 3: compute the number of chunks and find the ones which were already appended before the job got resumed
+	// since chunks are appended in order, the appended chunks precede the first chunk which is not complete
+	uploadChunkSize := int64(transfer.ChunkSize)
+	numOfChunks := computeNumOfChunks(blobSize, uploadChunkSize)
+	chunksAppended := uint32(0)
+	for ; chunksAppended < numOfChunks; chunksAppended++ {
+		if chunkStatus, _ := getChunkStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, uint16(chunksAppended), transfer.JobHandlerMap); chunkStatus != ChunkTransferStatusComplete {
+			break
+		}
+	}
+
+	// step 4: create the append blob, unless it holds chunks appended before the job got resumed
+	if chunksAppended == 0 {
+		_, err := appendBlobUrl.Create(transfer.TransferCtx, azblob.BlobHTTPHeaders{}, azblob.Metadata{}, azblob.BlobAccessConditions{})
+		if err != nil {
+			logger.Error("failed to create the append blob of Transfer job with %s due to error %s", transferIdentifierStr, err.Error())
+			updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+			return
+		}
+	}
+
+	// step 5: if every chunk is appended already (or the file is empty), the transfer is complete
+	if chunksAppended == numOfChunks {
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusComplete, transfer.JobHandlerMap)
+		return
+	}
+
+	// step 6: map in the file to upload before transferring chunks
+	memoryMappedFile, refCount, ok := mapSourceFile(transfer)
+	if !ok {
+		return
+	}
+
+	// step 7: schedule the first chunk which is not appended yet, each chunk then schedules the next one
+	var newChunkMsg func(chunkId uint32) ChunkMsg
+	newChunkMsg = func(chunkId uint32) ChunkMsg {
+		startIndex := int64(chunkId) * uploadChunkSize
+		adjustedChunkSize := uploadChunkSize
+
+		// compute actual size of the chunk
+		if startIndex+uploadChunkSize > blobSize {
+			adjustedChunkSize = blobSize - startIndex
+		}
+
+		return ChunkMsg{
+			jobId:            transfer.JobId,
+			partNumber:       transfer.PartNumber,
+			jPartPlanInfoMap: transfer.JobHandlerMap,
+			doTransfer: generateAppendBlockFunc(
+				transfer.JobId,
+				transfer.PartNumber,
+				transfer.TransferId,
+				chunkId,
+				numOfChunks,
+				adjustedChunkSize,
+				startIndex,
+				appendBlobUrl,
+				memoryMappedFile,
+				refCount,
+				transfer.TransferCtx,
+				transfer.TransferCancelFunc,
+				func() {
+					refCount.scheduleAsync(newChunkMsg(chunkId+1), chunkChannel)
+				},
+				transfer.JobHandlerMap),
+		}
+	}
+	refCount.schedule(newChunkMsg(chunksAppended), chunkChannel)
+	refCount.done()
+}
+
+// this generates a function which appends a single chunk to the append blob
+// scheduleNextChunk is called once the chunk is appended, unless it is the last chunk
+func generateAppendBlockFunc(jobId common.JobID, partNum common.PartNumber, transferId uint32, chunkId uint32, totalNumOfChunks uint32, chunkSize int64, startIndex int64, appendBlobUrl azblob.AppendBlobURL,
+	memoryMappedFile mmap.MMap, refCount *chunkRefCount, ctx context.Context, cancelTransfer func(), scheduleNextChunk func(), jPartPlanInfoMap *JobPartPlanInfoMap) chunkFunc {
+	return func(workerId int) {
+		logger := getLoggerFromJobPartPlanInfo(jobId, partNum, jPartPlanInfoMap)
+		transferIdentifierStr := fmt.Sprintf("jobId %s and partNum %d and transferId %d", jobId, partNum, transferId)
+		defer refCount.done()
+
+		if isChunkCancelled(ctx, workerId, int32(chunkId), transferIdentifierStr, logger) {
+			return
+		}
+
+		// step 1: append the block, at the position right after the previously appended chunks
+		// the condition keeps the chunk from being appended twice, if it got appended by a request which was retried
+		// or before the job got resumed without being recorded, in which case the blob already ends with the chunk
+		startTime := time.Now()
+		_, err := appendBlobUrl.AppendBlock(ctx, bytes.NewReader(memoryMappedFile[startIndex:startIndex+chunkSize]),
+			azblob.BlobAccessConditions{AppendBlobAccessConditions: azblob.AppendBlobAccessConditions{IfAppendPositionEqual: startIndex}})
+		if err != nil && isAppendPositionNotMet(err) {
+			err = verifyChunkAppended(ctx, appendBlobUrl, startIndex+chunkSize)
+		}
+		if err != nil {
+			// cancel entire transfer because this chunk has failed
+			cancelTransfer()
+			logger.Debug("worker %d is canceling Chunk job with %s and chunkId %d because startIndex of %d has failed due to error %s", workerId, transferIdentifierStr, chunkId, startIndex, err.Error())
+			updateChunkInfo(jobId, partNum, transferId, uint16(chunkId), [128 / 8]byte{}, ChunkTransferStatusFailed, jPartPlanInfoMap)
+			updateTransferStatus(jobId, partNum, transferId, common.TransferStatusFailed, jPartPlanInfoMap)
+			return
+		}
+
+		// the chunk is persisted as complete, so that a resumed job continues after it
+		updateChunkInfo(jobId, partNum, transferId, uint16(chunkId), [128 / 8]byte{}, ChunkTransferStatusComplete, jPartPlanInfoMap)
+		recordChunkLatency(jobId, partNum, time.Since(startTime), jPartPlanInfoMap)
+		updateThroughputCounter(chunkSize)
+
+		// step 2: schedule the next chunk, unless this is the last chunk
+		if chunkId+1 < totalNumOfChunks {
+			scheduleNextChunk()
+			return
+		}
+
+		// step 3: this is the last chunk, perform EPILOGUE
+		logger.Debug("worker %d is concluding upload Transfer job with %s after processing chunkId %d", workerId, transferIdentifierStr, chunkId)
+		updateTransferStatus(jobId, partNum, transferId, common.TransferStatusComplete, jPartPlanInfoMap)
+	}
+}
+
+// isAppendPositionNotMet returns whether the append of a block got refused since the blob does not end at the expected position
+func isAppendPositionNotMet(err error) bool {
+	storageErr, ok := err.(azblob.StorageError)
+	return ok && storageErr.Response() != nil && storageErr.Response().StatusCode == http.StatusPreconditionFailed
+}
+
+// verifyChunkAppended checks that the append blob ends right after the chunk whose append got refused
+// the chunk was appended already then, otherwise the blob got changed by someone else and an error is returned
+func verifyChunkAppended(ctx context.Context, appendBlobUrl azblob.AppendBlobURL, chunkEndIndex int64) error {
+	blobProperties, err := appendBlobUrl.GetPropertiesAndMetadata(ctx, azblob.BlobAccessConditions{})
+	if err != nil {
+		return err
+	}
+	if blobProperties.ContentLength() != chunkEndIndex {
+		return fmt.Errorf("the append blob is %d bytes long instead of %d bytes", blobProperties.ContentLength(), chunkEndIndex)
+	}
+	return nil
+}
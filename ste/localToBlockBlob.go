@@ -3,62 +3,120 @@ package ste
 import (
 	"context"
 	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
-	"net/url"
 	"os"
 	"time"
-	"github.com/edsrzf/mmap-go"
-	"encoding/base64"
+	"io"
 	"bytes"
 	"sync/atomic"
 	"github.com/Azure/azure-storage-azcopy/common"
 	"fmt"
 )
 
-type localToBlockBlob struct {
-	// count the number of chunks that are done
-	count uint32
-}
+type localToBlockBlob struct{}
+
+// chunkReader returns the data of the chunk of the source which starts at startIndex
+type chunkReader func(ctx context.Context, startIndex int64, chunkSize int64) (io.ReadSeeker, error)
 
 // this function performs the setup for each transfer and schedules the corresponding chunkMsgs into the chunkChannel
 func (localToBlockBlob localToBlockBlob) prologue(transfer TransferMsgDetail, chunkChannel chan<- ChunkMsg) {
+	logger := getLoggerFromJobPartPlanInfo(transfer.JobId, transfer.PartNumber, transfer.JobHandlerMap)
+	transferIdentifierStr := fmt.Sprintf("jobId %s and partNum %d and transferId %d", transfer.JobId, transfer.PartNumber, transfer.TransferId)
+
 	// step 1: create pipeline for the destination blob
-	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{
-		Retry: azblob.RetryOptions{
-			Policy:        azblob.RetryPolicyExponential,
-			MaxTries:      3,
-			TryTimeout:    time.Second * 60,
-			RetryDelay:    time.Second * 1,
-			MaxRetryDelay: time.Second * 3,
-		},
-	})
-	u, _ := url.Parse(transfer.Destination)
-	blobUrl := azblob.NewBlobURL(*u, p)
-
-	// step 2: get the file size
-	fi, _ := os.Stat(transfer.Source)
-	blobSize := fi.Size()
+	blobUrl := newTransferBlobURL(transfer.Destination)
 
-	// step 3: map in the file to upload before transferring chunks
-	memoryMappedFile := openAndMemoryMapFile(transfer.Source)
+	// step 2: get the file size, the blocks uploaded before the job got resumed are only reused if the file did not change meanwhile
+	fi, err := os.Stat(transfer.Source)
+	if err == nil {
+		err = restartTransferIfSourceChanged(transfer, fi.Size(), fi.ModTime())
+	}
+	if err != nil {
+		logger.Error("failed to get the properties of the source of Transfer job with %s due to error %s", transferIdentifierStr, err.Error())
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+		return
+	}
+	blobSize := fi.Size()
 
-	// step 4: compute the number of blocks and create a slice to hold the blockIDs of each chunk
-	downloadChunkSize := int64(transfer.ChunkSize)
+	// step 3: upload the chunks of the file as blocks, the file is mapped in only if there is a chunk left to upload
+	scheduleBlockUploads(transfer, chunkChannel, blobUrl, blobSize, azblob.BlobHTTPHeaders{}, azblob.Metadata{},
+		func() (chunkReader, func(), error) {
+			memoryMappedFile, err := openAndMemoryMapFile(transfer.Source)
+			if err != nil {
+				return nil, nil, err
+			}
+			readChunk := func(ctx context.Context, startIndex int64, chunkSize int64) (io.ReadSeeker, error) {
+				return bytes.NewReader(memoryMappedFile[startIndex : startIndex+chunkSize]), nil
+			}
+			releaseSource := func() {
+				err := memoryMappedFile.Unmap()
+				if err != nil {
+					logger := getLoggerFromJobPartPlanInfo(transfer.JobId, transfer.PartNumber, transfer.JobHandlerMap)
+					logger.Error("failed to unmap the source of Transfer job with jobId %s and partNum %d and transferId %d", transfer.JobId, transfer.PartNumber, transfer.TransferId)
+				}
+			}
+			return readChunk, releaseSource, nil
+		})
+}
 
-	numOfBlocks := computeNumOfChunks(blobSize, downloadChunkSize)
+// scheduleBlockUploads schedules a chunkMsg uploading each chunk of the source as a block, the last chunk commits the block list
+// chunks uploaded before the job got resumed are recovered from the job part plan and not uploaded again
+// openSource is only called when there is a chunk left to upload, it returns the function reading the chunks
+// and the function releasing the source once every chunk scheduled is done, which may be nil
+// the transfer fails if the source cannot be opened
+func scheduleBlockUploads(transfer TransferMsgDetail, chunkChannel chan<- ChunkMsg, blobUrl azblob.BlobURL, sourceSize int64,
+	headers azblob.BlobHTTPHeaders, metadata azblob.Metadata, openSource func() (chunkReader, func(), error)) {
+	// step 1: compute the number of blocks and create a slice to hold the blockIDs of each chunk
+	uploadChunkSize := int64(transfer.ChunkSize)
+	numOfBlocks := computeNumOfChunks(sourceSize, uploadChunkSize)
 	blocksIds := make([]string, numOfBlocks)
-	blockIdCount := int32(0)
+	count := uint32(0)
+
+	// step 2: recover the blockIDs of the chunks which were already uploaded before the job got resumed
+	for chunkIndex := uint32(0); chunkIndex < numOfBlocks; chunkIndex++ {
+		chunkStatus, blockId := getChunkStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, uint16(chunkIndex), transfer.JobHandlerMap)
+		if chunkStatus == ChunkTransferStatusComplete {
+			blocksIds[chunkIndex] = encodeBlockId(blockId)
+			count += 1
+		}
+	}
+
+	// step 3: if every block is uploaded already (or the source is empty), only the block list is left to commit
+	if count == numOfBlocks {
+		commitBlockList(transfer.JobId, transfer.PartNumber, transfer.TransferId, blobUrl, transfer.TransferCtx, blocksIds, headers, metadata, transfer.JobHandlerMap)
+		return
+	}
+
+	// step 4: open the source before transferring chunks
+	readChunk, releaseSource, err := openSource()
+	if err != nil {
+		logger := getLoggerFromJobPartPlanInfo(transfer.JobId, transfer.PartNumber, transfer.JobHandlerMap)
+		logger.Error("failed to open the source of Transfer job with jobId %s and partNum %d and transferId %d due to error %s", transfer.JobId, transfer.PartNumber, transfer.TransferId, err.Error())
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+		return
+	}
 
-	// step 5: go through the file and schedule chunk messages to upload each chunk
-	for startIndex := int64(0); startIndex < blobSize; startIndex += downloadChunkSize {
-		adjustedChunkSize := downloadChunkSize
+	// step 5: go through the source and schedule chunk messages to upload each chunk which is not uploaded yet
+	// the source is released once every chunk scheduled is done, however the chunks end
+	refCount := newChunkRefCount(releaseSource)
+	blockIdCount := int32(0)
+	for startIndex := int64(0); startIndex < sourceSize; startIndex += uploadChunkSize {
+		adjustedChunkSize := uploadChunkSize
 
 		// compute actual size of the chunk
-		if startIndex+downloadChunkSize > blobSize {
-			adjustedChunkSize = blobSize - startIndex
+		if startIndex+uploadChunkSize > sourceSize {
+			adjustedChunkSize = sourceSize - startIndex
+		}
+
+		if blocksIds[blockIdCount] != "" {
+			blockIdCount += 1
+			continue
 		}
 
 		// schedule the chunk job/msg
-		chunkChannel <- ChunkMsg{
+		refCount.schedule(ChunkMsg{
+			jobId:            transfer.JobId,
+			partNumber:       transfer.PartNumber,
+			jPartPlanInfoMap: transfer.JobHandlerMap,
 			doTransfer: generateUploadFunc(
 				transfer.JobId,
 				transfer.PartNumber,
@@ -68,68 +126,87 @@ func (localToBlockBlob localToBlockBlob) prologue(transfer TransferMsgDetail, ch
 				adjustedChunkSize,
 				startIndex,
 				blobUrl,
-				memoryMappedFile,
+				readChunk,
+				refCount,
 				transfer.TransferCtx,
 				transfer.TransferCancelFunc,
-				&localToBlockBlob.count,
-				&blocksIds, transfer.JobHandlerMap),
-		}
+				&count,
+				&blocksIds,
+				headers,
+				metadata,
+				transfer.JobHandlerMap),
+		}, chunkChannel)
 		blockIdCount += 1
 	}
+	refCount.done()
 }
 
 // this generates a function which performs the uploading of a single chunk
 func generateUploadFunc(jobId common.JobID, partNum common.PartNumber, transferId uint32, chunkId int32, totalNumOfChunks uint32, chunkSize int64, startIndex int64, blobURL azblob.BlobURL,
-	memoryMappedFile mmap.MMap, ctx context.Context, cancelTransfer func(), progressCount *uint32, blockIds *[]string, jPartPlanInfoMap *JobPartPlanInfoMap) chunkFunc {
+	readChunk chunkReader, refCount *chunkRefCount, ctx context.Context, cancelTransfer func(), progressCount *uint32, blockIds *[]string,
+	headers azblob.BlobHTTPHeaders, metadata azblob.Metadata, jPartPlanInfoMap *JobPartPlanInfoMap) chunkFunc {
 	return func(workerId int) {
 		logger := getLoggerFromJobPartPlanInfo(jobId, partNum, jPartPlanInfoMap)
 		transferIdentifierStr := fmt.Sprintf("jobId %s and partNum %d and transferId %d", jobId, partNum, transferId)
+		defer refCount.done()
+
+		if isChunkCancelled(ctx, workerId, chunkId, transferIdentifierStr, logger) {
+			return
+		}
 
 		// step 1: generate block ID
-		blockId, _ := common.NewUUID()
-		encodedBlockId := base64.StdEncoding.EncodeToString([]byte(blockId))
+		blockId := newBlockId()
+		encodedBlockId := encodeBlockId(blockId)
 
 		// step 2: save the block ID into the list of block IDs
 		(*blockIds)[chunkId] = encodedBlockId
 		//fmt.Println("Worker", workerId, "is processing upload CHUNK job with", transferIdentifierStr, "and chunkID", chunkId, "and blockID", encodedBlockId)
 
-		// step 3: perform put block
+		// step 3: read the chunk and perform put block
 		blockBlobUrl := blobURL.ToBlockBlobURL()
-		_, err := blockBlobUrl.PutBlock(ctx, encodedBlockId, bytes.NewReader(memoryMappedFile[startIndex: startIndex + chunkSize]), azblob.LeaseAccessConditions{})
+		startTime := time.Now()
+		body, err := readChunk(ctx, startIndex, chunkSize)
+		if err == nil {
+			_, err = blockBlobUrl.PutBlock(ctx, encodedBlockId, body, azblob.LeaseAccessConditions{})
+		}
 		if err != nil {
 			// cancel entire transfer because this chunk has failed
 			cancelTransfer()
-			logger.Debug("worker %d is canceling Chunk job with %s and chunkId %d because startIndex of %d has failed", workerId, transferIdentifierStr, chunkId, startIndex)
+			logger.Debug("worker %d is canceling Chunk job with %s and chunkId %d because startIndex of %d has failed due to error %s", workerId, transferIdentifierStr, chunkId, startIndex, err.Error())
 			//fmt.Println("Worker", workerId, "is canceling CHUNK job with", transferIdentifierStr, "and chunkID", chunkId, "because startIndex of", startIndex, "has failed due to err", err)
-			updateChunkInfo(jobId, partNum, transferId, uint16(chunkId), ChunkTransferStatusFailed, jPartPlanInfoMap)
+			updateChunkInfo(jobId, partNum, transferId, uint16(chunkId), blockId, ChunkTransferStatusFailed, jPartPlanInfoMap)
 			updateTransferStatus(jobId, partNum, transferId, common.TransferStatusFailed, jPartPlanInfoMap)
 			return
 		}
 
-		updateChunkInfo(jobId, partNum, transferId, uint16(chunkId), ChunkTransferStatusComplete, jPartPlanInfoMap)
+		// the block ID is persisted, so that a resumed job does not upload this chunk again
+		updateChunkInfo(jobId, partNum, transferId, uint16(chunkId), blockId, ChunkTransferStatusComplete, jPartPlanInfoMap)
+		recordChunkLatency(jobId, partNum, time.Since(startTime), jPartPlanInfoMap)
 		updateThroughputCounter(chunkSize)
 
 		// step 4: check if this is the last chunk
 		if atomic.AddUint32(progressCount, 1) == totalNumOfChunks {
 			// step 5: this is the last block, perform EPILOGUE
-			logger.Debug("worker %d is concluding download Transfer job with %s after processing chunkId %d with blocklist %s", workerId, transferIdentifierStr, chunkId, *blockIds)
+			logger.Debug("worker %d is concluding upload Transfer job with %s after processing chunkId %d with blocklist %s", workerId, transferIdentifierStr, chunkId, *blockIds)
 			//fmt.Println("Worker", workerId, "is concluding upload TRANSFER job with", transferIdentifierStr, "after processing chunkId", chunkId, "with blocklist", *blockIds)
 
-			_, err = blockBlobUrl.PutBlockList(ctx, *blockIds, azblob.Metadata{}, azblob.BlobHTTPHeaders{}, azblob.BlobAccessConditions{})
-			if err != nil {
-				logger.Error("Worker %d failed to conclude Transfer job with %s after processing chunkId %d due to error %s", workerId, transferIdentifierStr, chunkId, string(err.Error()))
-				//fmt.Println("Worker", workerId, "failed to conclude TRANSFER job with", transferIdentifierStr, "after processing chunkId", chunkId, "due to err", err)
-				updateTransferStatus(jobId, partNum, transferId, common.TransferStatusFailed, jPartPlanInfoMap)
-			}
-
-			updateTransferStatus(jobId, partNum, transferId, common.TransferStatusComplete, jPartPlanInfoMap)
-
-			err := memoryMappedFile.Unmap()
-			if err != nil {
-				logger.Error("worker %v failed to conclude Transfer job with %v after processing chunkId %v", workerId, transferIdentifierStr, chunkId)
-				//fmt.Println("Worker", workerId, "failed to conclude TRANSFER job with", transferIdentifierStr, "after processing chunkId", chunkId, "due to err", err)
-			}
-
+			commitBlockList(jobId, partNum, transferId, blobURL, ctx, *blockIds, headers, metadata, jPartPlanInfoMap)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// commitBlockList commits the uploaded blocks of a transfer, with the given headers and metadata, and concludes it
+func commitBlockList(jobId common.JobID, partNum common.PartNumber, transferId uint32, blobURL azblob.BlobURL,
+	ctx context.Context, blockIds []string, headers azblob.BlobHTTPHeaders, metadata azblob.Metadata, jPartPlanInfoMap *JobPartPlanInfoMap) {
+	logger := getLoggerFromJobPartPlanInfo(jobId, partNum, jPartPlanInfoMap)
+	transferIdentifierStr := fmt.Sprintf("jobId %s and partNum %d and transferId %d", jobId, partNum, transferId)
+
+	blockBlobUrl := blobURL.ToBlockBlobURL()
+	_, err := blockBlobUrl.PutBlockList(ctx, blockIds, metadata, headers, azblob.BlobAccessConditions{})
+	if err != nil {
+		logger.Error("failed to conclude Transfer job with %s due to error %s", transferIdentifierStr, string(err.Error()))
+		updateTransferStatus(jobId, partNum, transferId, common.TransferStatusFailed, jPartPlanInfoMap)
+	} else {
+		updateTransferStatus(jobId, partNum, transferId, common.TransferStatusComplete, jPartPlanInfoMap)
+	}
+}
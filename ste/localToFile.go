@@ -0,0 +1,146 @@
+package ste
+
+import (
+	"context"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/edsrzf/mmap-go"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type localToFile struct{}
+
+// this function performs the setup for each transfer and schedules the corresponding chunkMsgs into the chunkChannel
+// the file is created at its full size in the share, the chunks then write its ranges
+func (localToFile localToFile) prologue(transfer TransferMsgDetail, chunkChannel chan<- ChunkMsg) {
+	logger := getLoggerFromJobPartPlanInfo(transfer.JobId, transfer.PartNumber, transfer.JobHandlerMap)
+	transferIdentifierStr := fmt.Sprintf("jobId %s and partNum %d and transferId %d", transfer.JobId, transfer.PartNumber, transfer.TransferId)
+
+	// step 1: get the url of the destination file
+	u, _ := url.Parse(transfer.Destination)
+	fileUrl := common.NewFileURL(*u)
+
+	// step 2: get the file size, the ranges uploaded before the job got resumed are only reused if the file did not change meanwhile
+	fi, err := os.Stat(transfer.Source)
+	if err == nil {
+		err = restartTransferIfSourceChanged(transfer, fi.Size(), fi.ModTime())
+	}
+	if err != nil {
+		logger.Error("failed to get the properties of the source of Transfer job with %s due to error %s", transferIdentifierStr, err.Error())
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+		return
+	}
+	fileSize := fi.Size()
+
+	// step 3: compute the number of chunks and recover the ones which were already uploaded before the job got resumed
+	numOfChunks := computeNumOfChunks(fileSize, int64(transfer.ChunkSize))
+	chunksUploaded, numOfChunksUploaded := getCompletedChunks(transfer, numOfChunks)
+
+	// step 4: create the directories of the file and the file itself, unless it holds chunks uploaded before the job got resumed
+	if numOfChunksUploaded == 0 {
+		err := createParentDirectories(transfer.TransferCtx, *u)
+		if err == nil {
+			err = fileUrl.Create(transfer.TransferCtx, fileSize)
+		}
+		if err != nil {
+			logger.Error("failed to create the file of Transfer job with %s due to error %s", transferIdentifierStr, err.Error())
+			updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+			return
+		}
+	}
+
+	// step 5: if every chunk is uploaded already (or the file is empty), the transfer is complete
+	if numOfChunksUploaded == numOfChunks {
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusComplete, transfer.JobHandlerMap)
+		return
+	}
+
+	// step 6: map in the file to upload before transferring chunks
+	memoryMappedFile, refCount, ok := mapSourceFile(transfer)
+	if !ok {
+		return
+	}
+
+	// step 7: go through the file and schedule chunk messages to upload each range which is not uploaded yet
+	scheduleChunks(transfer, chunkChannel, fileSize, chunksUploaded, refCount,
+		func(chunkId int32, startIndex int64, chunkSize int64) chunkFunc {
+			return generatePutRangeFunc(
+				transfer.JobId,
+				transfer.PartNumber,
+				transfer.TransferId,
+				chunkId,
+				numOfChunks,
+				chunkSize,
+				startIndex,
+				fileUrl,
+				memoryMappedFile,
+				refCount,
+				transfer.TransferCtx,
+				transfer.TransferCancelFunc,
+				&numOfChunksUploaded,
+				transfer.JobHandlerMap)
+		})
+}
+
+// this generates a function which performs the uploading of a single range of the file
+func generatePutRangeFunc(jobId common.JobID, partNum common.PartNumber, transferId uint32, chunkId int32, totalNumOfChunks uint32, chunkSize int64, startIndex int64, fileUrl common.FileURL,
+	memoryMappedFile mmap.MMap, refCount *chunkRefCount, ctx context.Context, cancelTransfer func(), progressCount *uint32, jPartPlanInfoMap *JobPartPlanInfoMap) chunkFunc {
+	return func(workerId int) {
+		logger := getLoggerFromJobPartPlanInfo(jobId, partNum, jPartPlanInfoMap)
+		transferIdentifierStr := fmt.Sprintf("jobId %s and partNum %d and transferId %d", jobId, partNum, transferId)
+		defer refCount.done()
+
+		if isChunkCancelled(ctx, workerId, chunkId, transferIdentifierStr, logger) {
+			return
+		}
+
+		// step 1: put the range
+		startTime := time.Now()
+		err := fileUrl.PutRange(ctx, startIndex, memoryMappedFile[startIndex:startIndex+chunkSize])
+		if err != nil {
+			// cancel entire transfer because this chunk has failed
+			cancelTransfer()
+			logger.Debug("worker %d is canceling Chunk job with %s and chunkId %d because startIndex of %d has failed due to error %s", workerId, transferIdentifierStr, chunkId, startIndex, err.Error())
+			updateChunkInfo(jobId, partNum, transferId, uint16(chunkId), [128 / 8]byte{}, ChunkTransferStatusFailed, jPartPlanInfoMap)
+			updateTransferStatus(jobId, partNum, transferId, common.TransferStatusFailed, jPartPlanInfoMap)
+			return
+		}
+
+		// the chunk is persisted as complete, so that a resumed job does not upload it again
+		updateChunkInfo(jobId, partNum, transferId, uint16(chunkId), [128 / 8]byte{}, ChunkTransferStatusComplete, jPartPlanInfoMap)
+		recordChunkLatency(jobId, partNum, time.Since(startTime), jPartPlanInfoMap)
+		updateThroughputCounter(chunkSize)
+
+		// step 2: check if this is the last chunk
+		if atomic.AddUint32(progressCount, 1) == totalNumOfChunks {
+			// step 3: this is the last chunk, perform EPILOGUE
+			logger.Debug("worker %d is concluding upload Transfer job with %s after processing chunkId %d", workerId, transferIdentifierStr, chunkId)
+			updateTransferStatus(jobId, partNum, transferId, common.TransferStatusComplete, jPartPlanInfoMap)
+		}
+	}
+}
+
+// createParentDirectories creates the directories of the share which lead to the given file, from the root of the share down
+// directories which exist already are left as is
+func createParentDirectories(ctx context.Context, fileUrl url.URL) error {
+	sharePath, pathInShare := common.SplitFileUrlPath(fileUrl)
+	directoryUrl := fileUrl
+	directoryPath := sharePath
+	for _, directoryName := range strings.Split(path.Dir(pathInShare), "/") {
+		if directoryName == "." || directoryName == "" {
+			continue
+		}
+		directoryPath += "/" + directoryName
+		directoryUrl.Path = directoryPath
+		err := common.NewDirectoryURL(directoryUrl).CreateIfNotExists(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
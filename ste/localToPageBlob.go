@@ -0,0 +1,164 @@
+package ste
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
+	"github.com/edsrzf/mmap-go"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+type localToPageBlob struct{}
+
+// this function performs the setup for each transfer and schedules the corresponding chunkMsgs into the chunkChannel
+// the page blob is created at the full size of the file, the chunks then write the pages which hold data
+func (localToPageBlob localToPageBlob) prologue(transfer TransferMsgDetail, chunkChannel chan<- ChunkMsg) {
+	logger := getLoggerFromJobPartPlanInfo(transfer.JobId, transfer.PartNumber, transfer.JobHandlerMap)
+	transferIdentifierStr := fmt.Sprintf("jobId %s and partNum %d and transferId %d", transfer.JobId, transfer.PartNumber, transfer.TransferId)
+
+	// step 1: create pipeline for the destination blob
+	pageBlobUrl := newTransferBlobURL(transfer.Destination).ToPageBlobURL()
+
+	// step 2: get the file size, which has to be aligned to the page size
+	// the pages uploaded before the job got resumed are only reused if the file did not change meanwhile
+	fi, err := os.Stat(transfer.Source)
+	if err == nil {
+		err = restartTransferIfSourceChanged(transfer, fi.Size(), fi.ModTime())
+	}
+	if err != nil {
+		logger.Error("failed to get the properties of the source of Transfer job with %s due to error %s", transferIdentifierStr, err.Error())
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+		return
+	}
+	blobSize := fi.Size()
+	if blobSize%common.PageSize != 0 {
+		logger.Error("failed to upload Transfer job with %s since the size %d of the source is not a multiple of the page size %d", transferIdentifierStr, blobSize, common.PageSize)
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+		return
+	}
+
+	// step 3: compute the number of chunks and recover the ones which were already uploaded before the job got resumed
+	numOfChunks := computeNumOfChunks(blobSize, int64(transfer.ChunkSize))
+	chunksUploaded, numOfChunksUploaded := getCompletedChunks(transfer, numOfChunks)
+
+	// step 4: create the page blob, unless it holds chunks uploaded before the job got resumed
+	if numOfChunksUploaded == 0 {
+		_, err := pageBlobUrl.Create(transfer.TransferCtx, blobSize, 0, azblob.BlobHTTPHeaders{}, azblob.Metadata{}, azblob.BlobAccessConditions{})
+		if err != nil {
+			logger.Error("failed to create the page blob of Transfer job with %s due to error %s", transferIdentifierStr, err.Error())
+			updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+			return
+		}
+	}
+
+	// step 5: if every chunk is uploaded already (or the file is empty), the transfer is complete
+	if numOfChunksUploaded == numOfChunks {
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusComplete, transfer.JobHandlerMap)
+		return
+	}
+
+	// step 6: map in the file to upload before transferring chunks
+	memoryMappedFile, refCount, ok := mapSourceFile(transfer)
+	if !ok {
+		return
+	}
+
+	// step 7: go through the file and schedule chunk messages to upload each chunk which is not uploaded yet
+	scheduleChunks(transfer, chunkChannel, blobSize, chunksUploaded, refCount,
+		func(chunkId int32, startIndex int64, chunkSize int64) chunkFunc {
+			return generatePutPagesFunc(
+				transfer.JobId,
+				transfer.PartNumber,
+				transfer.TransferId,
+				chunkId,
+				numOfChunks,
+				chunkSize,
+				startIndex,
+				pageBlobUrl,
+				memoryMappedFile,
+				refCount,
+				transfer.TransferCtx,
+				transfer.TransferCancelFunc,
+				&numOfChunksUploaded,
+				transfer.JobHandlerMap)
+		})
+}
+
+// this generates a function which performs the uploading of the pages of a single chunk
+// the pages which are entirely zero are skipped, since a newly created page blob reads as zero already
+func generatePutPagesFunc(jobId common.JobID, partNum common.PartNumber, transferId uint32, chunkId int32, totalNumOfChunks uint32, chunkSize int64, startIndex int64, pageBlobUrl azblob.PageBlobURL,
+	memoryMappedFile mmap.MMap, refCount *chunkRefCount, ctx context.Context, cancelTransfer func(), progressCount *uint32, jPartPlanInfoMap *JobPartPlanInfoMap) chunkFunc {
+	return func(workerId int) {
+		logger := getLoggerFromJobPartPlanInfo(jobId, partNum, jPartPlanInfoMap)
+		transferIdentifierStr := fmt.Sprintf("jobId %s and partNum %d and transferId %d", jobId, partNum, transferId)
+		defer refCount.done()
+
+		if isChunkCancelled(ctx, workerId, chunkId, transferIdentifierStr, logger) {
+			return
+		}
+
+		// step 1: put the ranges of the chunk which hold data
+		startTime := time.Now()
+		uploadedBytes := int64(0)
+		for _, pageRange := range getNonZeroPageRanges(memoryMappedFile[startIndex:startIndex+chunkSize], startIndex) {
+			body := bytes.NewReader(memoryMappedFile[pageRange.Start : pageRange.End+1])
+			_, err := pageBlobUrl.PutPages(ctx, pageRange, body, azblob.BlobAccessConditions{})
+			if err != nil {
+				// cancel entire transfer because this chunk has failed
+				cancelTransfer()
+				logger.Debug("worker %d is canceling Chunk job with %s and chunkId %d because the pages from %d to %d have failed", workerId, transferIdentifierStr, chunkId, pageRange.Start, pageRange.End)
+				updateChunkInfo(jobId, partNum, transferId, uint16(chunkId), [128 / 8]byte{}, ChunkTransferStatusFailed, jPartPlanInfoMap)
+				updateTransferStatus(jobId, partNum, transferId, common.TransferStatusFailed, jPartPlanInfoMap)
+				return
+			}
+			uploadedBytes += pageRange.End - pageRange.Start + 1
+		}
+
+		// the chunk is persisted as complete, so that a resumed job does not upload it again
+		updateChunkInfo(jobId, partNum, transferId, uint16(chunkId), [128 / 8]byte{}, ChunkTransferStatusComplete, jPartPlanInfoMap)
+		recordChunkLatency(jobId, partNum, time.Since(startTime), jPartPlanInfoMap)
+		updateThroughputCounter(uploadedBytes)
+
+		// step 2: check if this is the last chunk
+		if atomic.AddUint32(progressCount, 1) == totalNumOfChunks {
+			// step 3: this is the last chunk, perform EPILOGUE
+			logger.Debug("worker %d is concluding upload Transfer job with %s after processing chunkId %d", workerId, transferIdentifierStr, chunkId)
+			updateTransferStatus(jobId, partNum, transferId, common.TransferStatusComplete, jPartPlanInfoMap)
+		}
+	}
+}
+
+// getNonZeroPageRanges returns the ranges of consecutive pages of data which are not entirely zero
+// offset is the position of data in the page blob, the returned ranges are positioned in the page blob
+func getNonZeroPageRanges(data []byte, offset int64) []azblob.PageRange {
+	var pageRanges []azblob.PageRange
+	rangeStart := int64(-1)
+	for pageStart := int64(0); pageStart < int64(len(data)); pageStart += common.PageSize {
+		if isZeroPage(data[pageStart : pageStart+common.PageSize]) {
+			if rangeStart != -1 {
+				pageRanges = append(pageRanges, azblob.PageRange{Start: offset + rangeStart, End: offset + pageStart - 1})
+				rangeStart = -1
+			}
+		} else if rangeStart == -1 {
+			rangeStart = pageStart
+		}
+	}
+	if rangeStart != -1 {
+		pageRanges = append(pageRanges, azblob.PageRange{Start: offset + rangeStart, End: offset + int64(len(data)) - 1})
+	}
+	return pageRanges
+}
+
+// isZeroPage returns whether every byte of the page is zero
+func isZeroPage(page []byte) bool {
+	for _, b := range page {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
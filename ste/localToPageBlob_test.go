@@ -0,0 +1,45 @@
+package ste
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
+	"reflect"
+	"testing"
+)
+
+func TestGetNonZeroPageRanges(t *testing.T) {
+	// pages builds data of the given pages, a page is zero unless its flag is set
+	pages := func(nonZero ...bool) []byte {
+		data := make([]byte, int64(len(nonZero))*common.PageSize)
+		for page, isNonZero := range nonZero {
+			if isNonZero {
+				// a single non-zero byte, at the end of the page, makes the page non-zero
+				data[int64(page+1)*common.PageSize-1] = 1
+			}
+		}
+		return data
+	}
+	testCases := []struct {
+		name     string
+		data     []byte
+		offset   int64
+		expected []azblob.PageRange
+	}{
+		{"no data", nil, 0, nil},
+		{"all zero pages", pages(false, false, false), 0, nil},
+		{"single non-zero page", pages(true), 0, []azblob.PageRange{{Start: 0, End: 511}}},
+		{"all non-zero pages", pages(true, true, true), 0, []azblob.PageRange{{Start: 0, End: 1535}}},
+		{"leading zero pages", pages(false, false, true), 0, []azblob.PageRange{{Start: 1024, End: 1535}}},
+		{"trailing zero pages", pages(true, false, false), 0, []azblob.PageRange{{Start: 0, End: 511}}},
+		{"interior zero pages", pages(true, true, false, false, true), 0,
+			[]azblob.PageRange{{Start: 0, End: 1023}, {Start: 2048, End: 2559}}},
+		// the ranges are positioned in the page blob
+		{"offset", pages(false, true, false, true), 4096,
+			[]azblob.PageRange{{Start: 4608, End: 5119}, {Start: 5632, End: 6143}}},
+	}
+	for _, testCase := range testCases {
+		if pageRanges := getNonZeroPageRanges(testCase.data, testCase.offset); !reflect.DeepEqual(pageRanges, testCase.expected) {
+			t.Errorf("%s: got page ranges %v, expected %v", testCase.name, pageRanges, testCase.expected)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package ste
 
 import (
 	"errors"
-	"time"
 	"fmt"
 	"os"
 	"github.com/edsrzf/mmap-go"
@@ -28,6 +27,7 @@ func (job *JobPartPlanInfo) initialize(jobContext context.Context, fileName stri
 	job.ctx, job.cancel = context.WithCancel(jobContext)
 
 	// memory map the JobPartOrder File with given filename
+	job.fileName = fileName
 	job.memMap = memoryMapTheJobFile(fileName)
 
 	// gets the memory map JobPartPlanHeader for given JobPartOrder
@@ -136,7 +136,7 @@ func (job *JobPartPlanInfo)updateTheChunkInfo(transferIndex uint32, chunkIndex u
 }
 
 // getChunkInfo returns the memory map JobPartPlanTransferChunkHeader for given transfer and chunk index of JobPartOrder
-func (job JobPartPlanInfo)getChunkInfo(transferIndex uint32, chunkIndex uint16)(*JobPartPlanTransferChunk){
+func (job *JobPartPlanInfo)getChunkInfo(transferIndex uint32, chunkIndex uint16)(*JobPartPlanTransferChunk){
 
 	// get memory map JobPartPlanHeader
 	jPartPlan := job.getJobPartPlanPointer()
@@ -151,7 +151,8 @@ func (job JobPartPlanInfo)getChunkInfo(transferIndex uint32, chunkIndex uint16)(
 		panic (errors.New(errorMsg))
 	}
 
-	chunkInfoOffset := tEntry.Offset
+	// chunk headers of a transfer are laid out one after the other starting at the transfer's offset
+	chunkInfoOffset := tEntry.Offset + (uint64(unsafe.Sizeof(JobPartPlanTransferChunk{})) * uint64(chunkIndex))
 
 	// chunkInfoByteSlice represents the slice of memorymap buffer starting from chunkInfoOffset
 	chunkInfoByteSlice := job.memMap[chunkInfoOffset :]
@@ -250,8 +251,8 @@ func createJobPartPlanFile(jobPartOrder common.CopyJobPartOrder, data JobPartPla
 		// currentTransferEntry represents the JobPartPlan Transfer Header of a transfer.
 		currentTransferEntry := JobPartPlanTransfer{currentTransferChunkOffset, uint16(len(jobPartOrder.Transfers[index].Source)),
 			uint16(len(jobPartOrder.Transfers[index].Destination)),
-			getNumChunks(jobPartOrder.Transfers[index], data),
-			uint32(jobPartOrder.Transfers[index].LastModifiedTime.Nanosecond()), common.TransferStatusActive, uint64(jobPartOrder.Transfers[index].SourceSize), 0}
+			getNumChunks(jobPartOrder.Transfers[index], jobPartOrder.DestinationType, data),
+			jobPartOrder.Transfers[index].LastModifiedTime.UnixNano(), common.TransferStatusActive, uint64(jobPartOrder.Transfers[index].SourceSize), 0}
 		numBytesWritten, err = writeInterfaceDataToWriter(file, &currentTransferEntry, uint64(unsafe.Sizeof(JobPartPlanTransfer{})))
 		if err != nil{
 			panic(err)
@@ -260,7 +261,7 @@ func createJobPartPlanFile(jobPartOrder common.CopyJobPartOrder, data JobPartPla
 		transferEntryOffsets[index] = currentTransferChunkOffset
 		currentEndOffsetOfFile += uint64(numBytesWritten)
 
-		currentTransferChunkOffset += uint64(currentTransferEntry.ChunkNum* uint16(unsafe.Sizeof(JobPartPlanTransferChunk{}))) +
+		currentTransferChunkOffset += uint64(currentTransferEntry.ChunkNum) * uint64(unsafe.Sizeof(JobPartPlanTransferChunk{})) +
 												uint64(currentTransferEntry.SrcLength) + uint64(currentTransferEntry.DstLength)
 	}
 
@@ -303,23 +304,28 @@ func createJobPartPlanFile(jobPartOrder common.CopyJobPartOrder, data JobPartPla
 // Creates the memory map Job Part Plan Header from CopyJobPartOrder and JobPartPlanBlobData
 func jobPartTojobPartPlan(jobPart common.CopyJobPartOrder, data JobPartPlanBlobData) (JobPartPlanHeader){
 	var jobID [128 /8] byte
-	versionID := jobPart.Version
+	// the header records the data schema version the file is laid out with
+	versionID := uint32(dataSchemaVersion)
 	// converting the job Id string to [128 / 8] byte format
 	jobID = convertJobIdToByteFormat(jobPart.ID)
 	partNo := jobPart.PartNum
-	TTA := uint32(time.Now().Nanosecond())
+	TTA := uint32(DefaultTTLAfterCompletion)
 
 	// calculating the number of transfer for given CopyJobPartOrder
 	numTransfer := uint32(len(jobPart.Transfers))
 	jPartInFile := JobPartPlanHeader{versionID, jobID, uint32(partNo),
 					jobPart.IsFinalPart,DefaultJobPriority, TTA,
 		jobPart.SourceType, jobPart.DestinationType,
-		numTransfer,  data}
+		numTransfer, jobPart.NumFilteredEntities, false, data}
 	return jPartInFile
 }
 
 // getNumChunks api returns the number of chunks depending on source Type and destination type
-func getNumChunks(transfer common.CopyTransfer, destBlobData JobPartPlanBlobData) uint16{
+func getNumChunks(transfer common.CopyTransfer, destinationType common.LocationType, destBlobData JobPartPlanBlobData) uint16{
+	// deleting the source is done in a single request, there is nothing to split into chunks
+	if destinationType == common.Delete {
+		return 0
+	}
 	var blockSize = uint64(0)
 	if destBlobData.BlockSize == 0{
 		blockSize = common.DefaultBlockSize
@@ -373,6 +379,6 @@ func dataToDestinationBlobData(data common.BlobTransferAttributes) (JobPartPlanB
 
 	return JobPartPlanBlobData{uint8(len(contentType)), contentTypeBytes,
 								uint8(len(contentEncoding)), contentEncodingBytes,
-								uint16(len(metaData)), metaDataBytes, uint64(blockSize)}, nil
+								uint16(len(metaData)), metaDataBytes, uint64(blockSize), data.BlobType}, nil
 }
 
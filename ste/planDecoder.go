@@ -0,0 +1,139 @@
+package ste
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/edsrzf/mmap-go"
+	"os"
+	"unsafe"
+)
+
+// DecodeJobPartPlanFile decodes the job part plan file with given name, without the transfer engine running
+// the file is memory mapped read only, so that it can be inspected while the transfer engine uses it
+func DecodeJobPartPlanFile(fileName string) (common.JobPartPlanDetails, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return common.JobPartPlanDetails{}, err
+	}
+	defer file.Close()
+
+	memMap, err := mmap.Map(file, mmap.RDONLY, 0)
+	if err != nil {
+		return common.JobPartPlanDetails{}, fmt.Errorf("error memory mapping the file %s with err %s", fileName, err.Error())
+	}
+	defer memMap.Unmap()
+
+	// the job part plan file is read through the same accessors the transfer engine uses
+	job := &JobPartPlanInfo{fileName: fileName, memMap: memMap}
+	err = validateJobPartPlanLayout(job)
+	if err != nil {
+		return common.JobPartPlanDetails{}, fmt.Errorf("%s is not a valid job part plan file: %s", fileName, err.Error())
+	}
+
+	jPartPlan := job.getJobPartPlanPointer()
+	blobData := jPartPlan.BlobData
+	details := common.JobPartPlanDetails{
+		FileName:            fileName,
+		Version:             jPartPlan.Version,
+		JobId:               common.JobID(convertJobIdBytesToString(jPartPlan.Id)),
+		PartNum:             jPartPlan.PartNum,
+		IsFinalPart:         jPartPlan.IsFinalPart,
+		Priority:            jPartPlan.Priority,
+		TTLAfterCompletion:  jPartPlan.TTLAfterCompletion,
+		SrcLocationType:     jPartPlan.SrcLocationType,
+		DstLocationType:     jPartPlan.DstLocationType,
+		NumTransfers:        jPartPlan.NumTransfers,
+		NumFilteredEntities: jPartPlan.NumFilteredEntities,
+		IsCancelled:         jPartPlan.IsCancelled,
+		ContentType:         string(blobData.ContentType[:blobData.ContentTypeLength]),
+		ContentEncoding:     string(blobData.ContentEncoding[:blobData.ContentEncodingLength]),
+		Metadata:            string(blobData.MetaData[:blobData.MetaDataLength]),
+		BlockSize:           blobData.BlockSize,
+		BlobType:            blobData.BlobType,
+		Transfers:           make([]common.JobPartPlanTransferDetails, jPartPlan.NumTransfers),
+	}
+
+	for index := uint32(0); index < jPartPlan.NumTransfers; index++ {
+		transferHeader := job.Transfer(index)
+		source, destination := job.getTransferSrcDstDetail(index)
+		transferDetails := common.JobPartPlanTransferDetails{
+			Src:            source,
+			Dst:            destination,
+			Status:         transferStatusToString(transferHeader.Status),
+			SourceSize:     transferHeader.SourceSize,
+			ModifiedTime:   transferHeader.ModifiedTime,
+			CompletionTime: transferHeader.CompletionTime,
+			Chunks:         make([]common.JobPartPlanChunkDetails, transferHeader.ChunkNum),
+		}
+		for chunkIndex := uint16(0); chunkIndex < transferHeader.ChunkNum; chunkIndex++ {
+			chunkInfo := job.getChunkInfo(index, chunkIndex)
+			chunkDetails := common.JobPartPlanChunkDetails{Status: chunkStatusToString(chunkInfo.Status)}
+			// chunks which are not uploaded as blocks have an empty block id
+			if chunkInfo.BlockId != [128 / 8]byte{} {
+				chunkDetails.BlockId = encodeBlockId(chunkInfo.BlockId)
+			}
+			transferDetails.Chunks[chunkIndex] = chunkDetails
+		}
+		details.Transfers[index] = transferDetails
+	}
+	return details, nil
+}
+
+// validateJobPartPlanLayout verifies that the header, the transfers and the chunks of the job part plan file lie within the file
+// so that a truncated or foreign file is reported instead of crashing the decoding
+func validateJobPartPlanLayout(job *JobPartPlanInfo) error {
+	fileSize := uint64(len(job.memMap))
+	headerSize := uint64(unsafe.Sizeof(JobPartPlanHeader{}))
+	if fileSize < headerSize {
+		return errors.New("the file is smaller than the job part plan header")
+	}
+
+	jPartPlan := job.getJobPartPlanPointer()
+	if jPartPlan.Version != dataSchemaVersion {
+		return fmt.Errorf("the data schema version %d of the file differs from the data schema version %d of this build", jPartPlan.Version, dataSchemaVersion)
+	}
+	if jPartPlan.BlobData.MetaDataLength > MAX_SIZE_META_DATA {
+		return fmt.Errorf("the metadata length %d exceeds the max metadata size %d", jPartPlan.BlobData.MetaDataLength, MAX_SIZE_META_DATA)
+	}
+	if headerSize+uint64(unsafe.Sizeof(JobPartPlanTransfer{}))*uint64(jPartPlan.NumTransfers) > fileSize {
+		return fmt.Errorf("the file is too small to hold its %d transfers", jPartPlan.NumTransfers)
+	}
+	for index := uint32(0); index < jPartPlan.NumTransfers; index++ {
+		transferHeader := job.Transfer(index)
+		transferEnd := transferHeader.Offset + uint64(unsafe.Sizeof(JobPartPlanTransferChunk{}))*uint64(transferHeader.ChunkNum) +
+			uint64(transferHeader.SrcLength) + uint64(transferHeader.DstLength)
+		if transferEnd > fileSize {
+			return fmt.Errorf("the chunks, source or destination of transfer %d lie beyond the end of the file", index)
+		}
+	}
+	return nil
+}
+
+// transferStatusToString returns the name of the transfer status, which might be unknown in a corrupted file
+func transferStatusToString(status common.Status) string {
+	switch status {
+	case common.TransferStatusActive, common.TransferStatusComplete, common.TransferStatusFailed, common.TransferStatusCancelled:
+		return common.TransferStatusCodeToString(status)
+	default:
+		return fmt.Sprintf("Unknown(%d)", status)
+	}
+}
+
+// chunkStatusToString returns the name of the chunk status
+func chunkStatusToString(status uint8) string {
+	switch status {
+	case ChunkTransferStatusInactive:
+		return "Inactive"
+	case ChunkTransferStatusActive:
+		return "Active"
+	case ChunkTransferStatusProgress:
+		return "Progress"
+	case ChunkTransferStatusComplete:
+		return "Complete"
+	case ChunkTransferStatusFailed:
+		return "Failed"
+	default:
+		return fmt.Sprintf("Unknown(%d)", status)
+	}
+}
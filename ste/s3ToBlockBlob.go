@@ -0,0 +1,112 @@
+package ste
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+type s3ToBlockBlob struct{}
+
+// this function performs the setup for each transfer and schedules the corresponding chunkMsgs into the chunkChannel
+// each chunk gets a range of the S3 object and uploads it as a block of the destination blob
+// objects of GCS are copied the same way, through the S3-compatible XML API of GCS
+func (s3ToBlockBlob s3ToBlockBlob) prologue(transfer TransferMsgDetail, chunkChannel chan<- ChunkMsg) {
+	logger := getLoggerFromJobPartPlanInfo(transfer.JobId, transfer.PartNumber, transfer.JobHandlerMap)
+	transferIdentifierStr := fmt.Sprintf("jobId %s and partNum %d and transferId %d", transfer.JobId, transfer.PartNumber, transfer.TransferId)
+
+	// step 1: create pipeline for the destination blob
+	blobUrl := newTransferBlobURL(transfer.Destination)
+
+	// step 2: get the object size and properties, with the credentials given in the job order
+	sourceUrl, _ := url.Parse(transfer.Source)
+	var s3Object common.S3URLParts
+	var s3Client common.S3Client
+	var err error
+	if transfer.SourceType == common.GCS {
+		s3Object = common.ParseGCSURL(*sourceUrl)
+		s3Client = common.NewGCSClient(transfer.SourceCredentials)
+	} else {
+		s3Object, err = common.ParseS3URL(*sourceUrl)
+		s3Client = common.NewS3Client(transfer.SourceCredentials)
+	}
+	// the blocks uploaded before the job got resumed are only reused if the object did not change meanwhile
+	var objectProperties common.S3ObjectProperties
+	if err == nil {
+		objectProperties, err = s3Client.GetObjectProperties(transfer.TransferCtx, s3Object)
+	}
+	if err == nil {
+		err = restartTransferIfSourceChanged(transfer, objectProperties.Size, objectProperties.LastModified)
+	}
+	if err != nil {
+		logger.Error("failed to get the properties of the source of Transfer job with %s due to error %s", transferIdentifierStr, err.Error())
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+		return
+	}
+	objectSize := objectProperties.Size
+
+	// the content type and the custom metadata of the object are kept by the blob
+	headers := azblob.BlobHTTPHeaders{ContentType: objectProperties.ContentType}
+	metadata, err := toBlobMetadata(objectProperties.Metadata)
+	if err != nil {
+		logger.Error("failed to convert the metadata of the source of Transfer job with %s due to error %s", transferIdentifierStr, err.Error())
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+		return
+	}
+
+	// step 3: upload each range of the object which is not uploaded yet as a block
+	scheduleBlockUploads(transfer, chunkChannel, blobUrl, objectSize, headers, metadata,
+		func() (chunkReader, func(), error) {
+			readChunk := func(ctx context.Context, startIndex int64, chunkSize int64) (io.ReadSeeker, error) {
+				body, err := s3Client.GetObjectRange(ctx, s3Object, startIndex, chunkSize)
+				if err != nil {
+					return nil, err
+				}
+				defer body.Close()
+				chunkData := make([]byte, chunkSize)
+				_, err = io.ReadFull(body, chunkData)
+				return bytes.NewReader(chunkData), err
+			}
+			return readChunk, nil, nil
+		})
+}
+
+// toBlobMetadata converts the custom metadata of an object into blob metadata
+// the names of blob metadata are C# identifiers, so other characters are replaced by underscores
+// returns an error if two names of the object convert to the same name, e.g. x-foo and x_foo, since only one of the values could be kept
+func toBlobMetadata(objectMetadata map[string]string) (azblob.Metadata, error) {
+	// the names are converted in order, so that the error names the same pair of names every time
+	names := make([]string, 0, len(objectMetadata))
+	for name := range objectMetadata {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metadata := azblob.Metadata{}
+	sourceNames := map[string]string{}
+	for _, name := range names {
+		blobName := []byte(name)
+		for i, c := range blobName {
+			if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_') {
+				blobName[i] = '_'
+			}
+		}
+		if len(blobName) == 0 || '0' <= blobName[0] && blobName[0] <= '9' {
+			blobName = append([]byte{'_'}, blobName...)
+		}
+		// the names of blob metadata are case insensitive
+		lowerBlobName := strings.ToLower(string(blobName))
+		if sourceName, ok := sourceNames[lowerBlobName]; ok {
+			return nil, fmt.Errorf("the metadata %s and %s of the object would both be named %s on the blob", sourceName, name, string(blobName))
+		}
+		sourceNames[lowerBlobName] = name
+		metadata[string(blobName)] = objectMetadata[name]
+	}
+	return metadata, nil
+}
@@ -0,0 +1,58 @@
+package ste
+
+import (
+	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToBlobMetadata(t *testing.T) {
+	testCases := []struct {
+		name           string
+		objectMetadata map[string]string
+		expected       azblob.Metadata
+	}{
+		{"no metadata", map[string]string{}, azblob.Metadata{}},
+		{"identifiers are kept", map[string]string{"author": "a", "Created_By2": "b"}, azblob.Metadata{"author": "a", "Created_By2": "b"}},
+		// characters which are not part of C# identifiers are replaced by underscores
+		{"dashes and dots", map[string]string{"x-foo": "a", "build.id": "b"}, azblob.Metadata{"x_foo": "a", "build_id": "b"}},
+		{"non ascii characters", map[string]string{"café": "a"}, azblob.Metadata{"caf__": "a"}},
+		// identifiers do not start with a digit
+		{"leading digit", map[string]string{"1st": "a"}, azblob.Metadata{"_1st": "a"}},
+	}
+	for _, testCase := range testCases {
+		metadata, err := toBlobMetadata(testCase.objectMetadata)
+		if err != nil {
+			t.Errorf("%s: got error %s", testCase.name, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(metadata, testCase.expected) {
+			t.Errorf("%s: got metadata %v, expected %v", testCase.name, metadata, testCase.expected)
+		}
+	}
+}
+
+// names which convert to the same name of blob metadata, compared case insensitively, fail the conversion
+func TestToBlobMetadataCollisions(t *testing.T) {
+	testCases := []struct {
+		name           string
+		objectMetadata map[string]string
+		expectedErr    string
+	}{
+		{"dash and underscore", map[string]string{"x_foo": "a", "x-foo": "b"}, "x-foo and x_foo"},
+		{"different cases", map[string]string{"foo": "a", "Foo": "b"}, "Foo and foo"},
+		{"different cases once converted", map[string]string{"x-Foo": "a", "x_foo": "b"}, "x-Foo and x_foo"},
+		{"leading digit", map[string]string{"_1st": "a", "1st": "b"}, "1st and _1st"},
+	}
+	for _, testCase := range testCases {
+		// the error names the same pair of names, whatever the order of the map
+		for attempt := 0; attempt < 10; attempt++ {
+			_, err := toBlobMetadata(testCase.objectMetadata)
+			if err == nil || !strings.Contains(err.Error(), testCase.expectedErr) {
+				t.Errorf("%s: got error %v, expected the metadata %s to collide", testCase.name, err, testCase.expectedErr)
+				break
+			}
+		}
+	}
+}
@@ -6,7 +6,7 @@ import (
 
 //These constant defines the various types of source and destination of the transfers
 
-const dataSchemaVersion = 0 // To be Incremented every time when we release azcopy with changed dataschema
+const dataSchemaVersion = 4 // To be Incremented every time when we release azcopy with changed dataschema
 
 // JobPartPlan represent the header of Job Part's Memory Map File
 type JobPartPlanHeader struct {
@@ -15,10 +15,12 @@ type JobPartPlanHeader struct {
 	PartNum uint32
 	IsFinalPart bool
 	Priority uint8
-	TTLAfterCompletion uint32
+	TTLAfterCompletion uint32 // number of seconds a completed job is kept before the transfer engine sweeps it
 	SrcLocationType common.LocationType
 	DstLocationType common.LocationType
 	NumTransfers uint32
+	NumFilteredEntities uint32 // number of entities left out of the part by the include/exclude filters
+	IsCancelled bool // set when the part is cancelled, so that it stays cancelled once the transfer engine restarts
 	//Status uint8
 	BlobData JobPartPlanBlobData
 }
@@ -32,6 +34,7 @@ type JobPartPlanBlobData struct {
 	MetaDataLength        uint16
 	MetaData              [1000]byte
 	BlockSize             uint64
+	BlobType              common.BlobType // type of the blobs the local files are uploaded as
 }
 
 // JobPartPlan represent the header of Job Part's Transfer in Memory Map File
@@ -40,10 +43,10 @@ type JobPartPlanTransfer struct {
 	SrcLength      uint16
 	DstLength      uint16
 	ChunkNum       uint16
-	ModifiedTime   uint32
+	ModifiedTime   int64 // last modified time of the source in nanoseconds since epoch, when the transfer got planned
 	Status         common.Status
 	SourceSize     uint64
-	CompletionTime uint64
+	CompletionTime uint64 // time in nanoseconds since epoch at which the transfer completed, failed or got cancelled
 }
 
 
@@ -62,6 +65,9 @@ const (
 
 
 
+// DefaultTTLAfterCompletion is the number of seconds a completed job is kept before the transfer engine sweeps it
+const DefaultTTLAfterCompletion = 7 * 24 * 60 * 60
+
 const (
 	HighJobPriority = 0
 	MediumJobPriority = 1
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/edsrzf/mmap-go"
 	"github.com/Azure/azure-storage-azcopy/common"
+	"sync"
 	"time"
 )
 
@@ -15,11 +16,32 @@ type TransferInfo struct {
 }
 
 type JobPartPlanInfo struct {
+	// ctxLock guards the contexts of the part and of its transfers, which get replaced when the part is resumed
+	ctxLock      sync.RWMutex
 	ctx          context.Context
 	cancel       context.CancelFunc
+	fileName     string
 	memMap       mmap.MMap
 	TrasnferInfo []TransferInfo
 	Logger 		*common.Logger
+	// isScheduled is true once the transfers of the part were scheduled by the current instance of transfer engine
+	isScheduled  bool
+	// pauseLock guards the paused state of the part and the messages parked by the workers while it is paused
+	pauseLock          sync.Mutex
+	isPaused           bool
+	parkedTransferMsgs []TransferMsg
+	parkedChunkMsgs    []ChunkMsg
+	// chunkLatencyLock guards the histogram of the latencies of the chunks transferred by the part
+	chunkLatencyLock sync.Mutex
+	chunkLatencies   latencyHistogram
+	// inFlightLock guards the number of msgs of the part in flight and whether the part got cleaned
+	// a msg is in flight while it is processed by a worker, and a chunk msg from the time it is scheduled until a worker is done with it
+	inFlightLock    sync.Mutex
+	numMsgsInFlight int
+	isCleaned       bool
+	// credentialsLock guards the credentials of an S3 or GCS source, which are kept in memory only
+	credentialsLock   sync.RWMutex
+	sourceCredentials common.S3Credentials
 }
 
 type TransferMsg struct {
@@ -27,6 +49,8 @@ type TransferMsg struct {
 	PartNumber common.PartNumber
 	TransferIndex uint32
 	JPartPlanInfoMap *JobPartPlanInfoMap
+	// the context the transfer had when the msg got scheduled, the msg is stale once the transfer got a new context on resume
+	TransferCtx context.Context
 }
 
 type TransferMsgDetail struct {
@@ -34,6 +58,7 @@ type TransferMsgDetail struct {
 	PartNumber 		common.PartNumber
 	TransferId      uint32
 	ChunkSize       uint64
+	BlobType        common.BlobType
 	SourceType      common.LocationType
 	Source          string
 	DestinationType common.LocationType
@@ -41,16 +66,23 @@ type TransferMsgDetail struct {
 	TransferCtx		context.Context
 	TransferCancelFunc func()
 	JobHandlerMap   *JobPartPlanInfoMap
+	SourceCredentials common.S3Credentials
 }
 
 type ChunkMsg struct {
-	doTransfer chunkFunc
+	// jobId, partNumber and jPartPlanInfoMap identify the job part of the chunk, so that chunks of a paused job can be parked
+	jobId            common.JobID
+	partNumber       common.PartNumber
+	jPartPlanInfoMap *JobPartPlanInfoMap
+	doTransfer       chunkFunc
 }
 
 type CoordinatorChannels struct{
 	HighTransfer chan <- TransferMsg
 	MedTransfer chan <- TransferMsg
 	LowTransfer chan <- TransferMsg
+	HighChunkTransaction chan <- ChunkMsg
+	WorkerCount chan <- int
 }
 
 type EEChannels struct {
@@ -60,7 +92,8 @@ type EEChannels struct {
 	HighChunkTransaction chan ChunkMsg
 	MedChunkTransaction  chan ChunkMsg
 	LowChunkTransaction  chan ChunkMsg
-	SuicideChannel       <- chan SuicideJob
+	SuicideChannel       chan SuicideJob
+	WorkerCount          <- chan int
 }
 
 type SuicideJob byte
@@ -2,6 +2,7 @@ package ste
 
 import (
 	"github.com/Azure/azure-storage-azcopy/common"
+	"crypto/rand"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -17,6 +18,7 @@ import (
 	"unsafe"
 	"io/ioutil"
 	"sync"
+	"time"
 )
 
 // JobToLoggerMap are the Synchronous Map to hold logger instance mapped to jobId
@@ -43,6 +45,13 @@ func (jLogger *JobToLoggerMap) StoreLoggerForJob(jobId common.JobID, logger *com
 	jLogger.Unlock()
 }
 
+// DeleteLoggerForJob removes the logger instance for given JobId in thread-safe manner
+func (jLogger *JobToLoggerMap) DeleteLoggerForJob(jobId common.JobID) {
+	jLogger.Lock()
+	delete(jLogger.internalMap, jobId)
+	jLogger.Unlock()
+}
+
 // NewJobToLoggerMap returns a new instance of synchronous JobToLoggerMap for holding logger instances mapped to JobIds
 func NewJobToLoggerMap() (*JobToLoggerMap){
 	return &JobToLoggerMap{
@@ -106,6 +115,13 @@ func (jMap *JobPartPlanInfoMap) StoreJobPartPlanInfo(jobId common.JobID, partNum
 	jMap.Unlock()
 }
 
+// DeleteJobInfoForJobId removes the JobPartPlanInfo references of all the parts of given JobId in thread-safe manner.
+func (jMap *JobPartPlanInfoMap) DeleteJobInfoForJobId(jobId common.JobID) {
+	jMap.Lock()
+	delete(jMap.internalMap, jobId)
+	jMap.Unlock()
+}
+
 // NewJobPartPlanInfoMap returns a new instance of synchronous JobPartPlanInfoMap to hold JobPartPlanInfo Pointer for given combination of JobId and part number.
 func NewJobPartPlanInfoMap() (*JobPartPlanInfoMap) {
 	return &JobPartPlanInfoMap{
@@ -158,7 +174,7 @@ func parseStringToJobInfo(s string) (jobId common.JobID, partNo common.PartNumbe
 // formatJobInfoToString builds the JobPart file name using the given JobId, part number and data schema version
 // fileName format := $jobId-$partnumber.stev$dataschemaversion
 func formatJobInfoToString(jobPartOrder common.CopyJobPartOrder) (string){
-	versionIdString := fmt.Sprintf("%05d", jobPartOrder.Version)
+	versionIdString := fmt.Sprintf("%05d", dataSchemaVersion)
 	partNoString := fmt.Sprintf("%05d", jobPartOrder.PartNum)
 	fileName := string(jobPartOrder.ID) + "-" + partNoString + ".stev" + versionIdString
 	return fileName
@@ -197,7 +213,7 @@ func convertJobIdBytesToString(jobId [128 /8]byte) (string){
 }
 
 // reconstructTheExistingJobPart reconstructs the in memory JobPartPlanInfo for existing memory map JobFile
-func reconstructTheExistingJobPart(jPartPlanInfoMap *JobPartPlanInfoMap) (error){
+func reconstructTheExistingJobPart(jPartPlanInfoMap *JobPartPlanInfoMap, jobToLoggerMap *JobToLoggerMap) (error){
 	versionIdString := fmt.Sprintf("%05d", dataSchemaVersion)
 	// list memory map files with .stev$dataschemaVersion to avoid the reconstruction of old schema version memory map file
 	files := listFileWithExtension(".stev" + versionIdString)
@@ -211,6 +227,18 @@ func reconstructTheExistingJobPart(jPartPlanInfoMap *JobPartPlanInfoMap) (error)
 		if err != nil{
 			return err
 		}
+		// a part cancelled before the transfer engine restarted stays cancelled until it is resumed
+		if jobHandler.getJobPartPlanPointer().IsCancelled{
+			jobHandler.cancel()
+		}
+		// the log verbosity is not part of the job part plan, so reconstructed jobs log with the default verbosity of the front-end
+		logger := getLoggerForJobId(jobIdString, jobToLoggerMap)
+		if logger == nil{
+			logger = new(common.Logger)
+			logger.Initialize(common.LOG_DEBUG_LEVEL, jobIdString)
+			jobToLoggerMap.StoreLoggerForJob(jobIdString, logger)
+		}
+		jobHandler.Logger = logger
 		// storing the JobPartPlanInfo pointer for given combination of JobId and part number
 		putJobPartInfoHandlerIntoMap(jobHandler, jobIdString, partNumber, jPartPlanInfoMap)
 	}
@@ -271,21 +299,85 @@ func getTransferMsgDetail (jobId common.JobID, partNo common.PartNumber, transfe
 	destinationType := jPartPlanPointer.DstLocationType
 	source, destination := jHandler.getTransferSrcDstDetail(transferEntryIndex)
 	chunkSize := jPartPlanPointer.BlobData.BlockSize
-	return TransferMsgDetail{jobId, partNo,transferEntryIndex, chunkSize, sourceType,
-		source, destinationType, destination, jHandler.TrasnferInfo[transferEntryIndex].ctx,
-						jHandler.TrasnferInfo[transferEntryIndex].cancel, jPartPlanInfoMap}
+	blobType := jPartPlanPointer.BlobData.BlobType
+	transferCtx, transferCancel := jHandler.transferContext(transferEntryIndex)
+	return TransferMsgDetail{jobId, partNo,transferEntryIndex, chunkSize, blobType, sourceType,
+		source, destinationType, destination, transferCtx, transferCancel, jPartPlanInfoMap, jHandler.getSourceCredentials()}
+}
+
+// restartTransferIfSourceChanged compares the size and the last modified time of the source with the ones the transfer got planned with
+// the chunks transferred before the job got resumed are only reused if the source did not change meanwhile, otherwise they are
+// marked inactive again so that the transfer restarts, and the plan takes the new size and last modified time of the source
+// returns an error if the source grew beyond the chunks planned for the transfer, since the transfer cannot restart then
+func restartTransferIfSourceChanged(transfer TransferMsgDetail, sourceSize int64, sourceModifiedTime time.Time) error {
+	jHandler, err := getJobPartInfoHandlerFromMap(transfer.JobId, transfer.PartNumber, transfer.JobHandlerMap)
+	if err != nil{
+		panic(err)
+	}
+	transferHeader := jHandler.Transfer(transfer.TransferId)
+	if transferHeader.SourceSize == uint64(sourceSize) && transferHeader.ModifiedTime == sourceModifiedTime.UnixNano(){
+		return nil
+	}
+	if sourceSize > 0 && computeNumOfChunks(sourceSize, int64(transfer.ChunkSize)) > uint32(transferHeader.ChunkNum){
+		return fmt.Errorf("the source grew from %d to %d bytes since the transfer got planned, so it has to be copied again", transferHeader.SourceSize, sourceSize)
+	}
+	numChunksDiscarded := 0
+	for chunkIndex := uint16(0); chunkIndex < transferHeader.ChunkNum; chunkIndex++{
+		if jHandler.getChunkInfo(transfer.TransferId, chunkIndex).Status == ChunkTransferStatusComplete{
+			numChunksDiscarded += 1
+		}
+		jHandler.updateTheChunkInfo(transfer.TransferId, chunkIndex, [128 / 8]byte{}, ChunkTransferStatusInactive)
+	}
+	transferHeader.SourceSize = uint64(sourceSize)
+	transferHeader.ModifiedTime = sourceModifiedTime.UnixNano()
+	if numChunksDiscarded > 0{
+		jHandler.Logger.Info("restarting transfer %d of job %s and part number %d since its source changed, %d chunks transferred before are discarded",
+			transfer.TransferId, transfer.JobId, transfer.PartNumber, numChunksDiscarded)
+	}
+	return nil
 }
 
 // updateChunkInfo updates the chunk at given chunkIndex for given JobId, partNumber and transfer
-func updateChunkInfo(jobId common.JobID, partNo common.PartNumber, transferEntryIndex uint32, chunkIndex uint16, status uint8, jPartPlanInfoMap *JobPartPlanInfoMap) {
+// blockId is the id of the block uploaded for the chunk, chunks which are not uploaded as blocks pass an empty id
+func updateChunkInfo(jobId common.JobID, partNo common.PartNumber, transferEntryIndex uint32, chunkIndex uint16, blockId [128 / 8]byte, status uint8, jPartPlanInfoMap *JobPartPlanInfoMap) {
 	jHandler, err := getJobPartInfoHandlerFromMap(jobId, partNo, jPartPlanInfoMap)
 	if err != nil{
 		panic(err)
 	}
-	resultMessage := jHandler.updateTheChunkInfo(transferEntryIndex, chunkIndex, [128 /8]byte{}, status)
+	resultMessage := jHandler.updateTheChunkInfo(transferEntryIndex, chunkIndex, blockId, status)
 	jHandler.Logger.Debug("%s for jobId %s and part number %d", resultMessage, jobId, partNo)
 }
 
+// getChunkStatus returns the status of the chunk at given chunkIndex for given JobId, partNumber and transfer
+// chunks beyond the ones planned for the transfer have never been transferred and are reported inactive
+func getChunkStatus(jobId common.JobID, partNo common.PartNumber, transferEntryIndex uint32, chunkIndex uint16, jPartPlanInfoMap *JobPartPlanInfoMap) (status uint8, blockId [128 / 8]byte) {
+	jHandler, err := getJobPartInfoHandlerFromMap(jobId, partNo, jPartPlanInfoMap)
+	if err != nil{
+		panic(err)
+	}
+	if chunkIndex >= jHandler.Transfer(transferEntryIndex).ChunkNum{
+		return ChunkTransferStatusInactive, blockId
+	}
+	chunkInfo := jHandler.getChunkInfo(transferEntryIndex, chunkIndex)
+	return chunkInfo.Status, chunkInfo.BlockId
+}
+
+// newBlockId generates a random id for a block to upload, which is persisted with the chunk in the job part plan
+func newBlockId() ([128 / 8]byte){
+	var blockId [128 / 8]byte
+	_, err := io.ReadFull(rand.Reader, blockId[:])
+	if err != nil{
+		panic(err)
+	}
+	return blockId
+}
+
+// encodeBlockId returns the base64 encoded form of the block id used in put block and put block list requests
+func encodeBlockId(blockId [128 / 8]byte) (string){
+	blockIdString := fmt.Sprintf("%x-%x-%x-%x-%x", blockId[0:4], blockId[4:6], blockId[6:8], blockId[8:10], blockId[10:])
+	return base64.StdEncoding.EncodeToString([]byte(blockIdString))
+}
+
 // updateTransferStatus updates the status of given transfer for given jobId and partNumber
 func updateTransferStatus(jobId common.JobID, partNo common.PartNumber, transferIndex uint32, transferStatus uint8, jPartPlanInfoMap *JobPartPlanInfoMap){
 	jHandler, err := getJobPartInfoHandlerFromMap(jobId, partNo, jPartPlanInfoMap)
@@ -293,7 +385,25 @@ func updateTransferStatus(jobId common.JobID, partNo common.PartNumber, transfer
 		panic (err)
 	}
 	transferHeader := jHandler.Transfer(transferIndex)
+	// requests aborted by the cancellation of a transfer must not mark the cancelled transfer as failed
+	if transferHeader.Status == common.TransferStatusCancelled && transferStatus == common.TransferStatusFailed{
+		return
+	}
 	transferHeader.Status = common.Status(transferStatus)
+	if transferStatus == common.TransferStatusComplete || transferStatus == common.TransferStatusFailed{
+		transferHeader.CompletionTime = uint64(time.Now().UnixNano())
+	}
+}
+
+// recordChunkLatency records the time taken to transfer a chunk of given JobId and partNumber
+func recordChunkLatency(jobId common.JobID, partNo common.PartNumber, latency time.Duration, jPartPlanInfoMap *JobPartPlanInfoMap){
+	jHandler, err := getJobPartInfoHandlerFromMap(jobId, partNo, jPartPlanInfoMap)
+	if err != nil{
+		panic(err)
+	}
+	jHandler.chunkLatencyLock.Lock()
+	jHandler.chunkLatencies.add(latency)
+	jHandler.chunkLatencyLock.Unlock()
 }
 
 // getLoggerForJobId returns the logger instance for a given JobId
@@ -33,8 +33,12 @@ import (
 	"time"
 	"sync/atomic"
 	"os"
+	"strconv"
 )
 var steContext = context.Background()
+
+// jobSweepIntervalEnvVar is the environment variable holding the interval at which the expired jobs are swept, e.g. "1h"
+const jobSweepIntervalEnvVar = "AZS_JOB_SWEEP_INTERVAL"
 var realTimeThroughputCounter = throughputState {lastCheckedBytes:0, currentBytes:0, lastCheckedTime:time.Now()}
 
 // putJobPartInfoHandlerIntoMap api put the JobPartPlanInfo pointer for given jobId and part number in map[common.JobID]map[common.PartNumber]*JobPartPlanInfo
@@ -114,8 +118,21 @@ func ExecuteNewCopyJobPartOrder(payload common.CopyJobPartOrder, coordiatorChann
 		jobToLoggerMap.StoreLoggerForJob(payload.ID, logger)
 	}
 	jobHandler.Logger = logger
+	jobHandler.setSourceCredentials(payload.SourceCredentials)
 	jobHandler.Logger.Info("new job part order received with job Id %s and part number %d", payload.ID, payload.PartNum)
+
+	// a part ordered after its job got cancelled is cancelled as well instead of being scheduled
+	jobIsCancelled := isJobCancelled(payload.ID, jPartPlanInfoMap)
+	// a part ordered while its job is paused is paused as well
+	if isJobPaused(payload.ID, jPartPlanInfoMap){
+		jobHandler.pause()
+	}
 	putJobPartInfoHandlerIntoMap(jobHandler, payload.ID, payload.PartNum, jPartPlanInfoMap)
+	if jobIsCancelled{
+		cancelJobPart(jobHandler)
+		jobHandler.Logger.Info("cancelled part number %d of job %s since the job was cancelled before", payload.PartNum, payload.ID)
+		return
+	}
 
 	if coordiatorChannels == nil{ // If the coordinator transfer channels are initialized properly, then incoming transfers can't be scheduled with current instance of transfer engine.
 		jobHandler.Logger.Error("coordinator channels not initialized properly")
@@ -123,19 +140,257 @@ func ExecuteNewCopyJobPartOrder(payload common.CopyJobPartOrder, coordiatorChann
 	// Scheduling each transfer in the new job according to the priority of the job
 	numTransfer := jobHandler.getJobPartPlanPointer().NumTransfers
 	for index := uint32(0); index < numTransfer; index ++{
-		transferMsg := TransferMsg{payload.ID, payload.PartNum, index, jPartPlanInfoMap}
-		switch payload.Priority{
-		case HighJobPriority:
-			coordiatorChannels.HighTransfer <- transferMsg
-			jobHandler.Logger.Debug("successfully scheduled transfer %v with priority %v for Job %v and part number %v", index, payload.Priority, string(payload.ID), payload.PartNum)
-		case MediumJobPriority:
-			coordiatorChannels.MedTransfer <- transferMsg
-		case LowJobPriority:
-			coordiatorChannels.LowTransfer <- transferMsg
-		default:
-			jobHandler.Logger.Debug("invalid job part order priority %d for given Job Id %s and part number %d and transfer Index %d", payload.Priority, payload.ID, payload.PartNum, index)
+		transferCtx, _ := jobHandler.transferContext(index)
+		transferMsg := TransferMsg{payload.ID, payload.PartNum, index, jPartPlanInfoMap, transferCtx}
+		scheduleTransfer(transferMsg, payload.Priority, coordiatorChannels, jobHandler.Logger)
+	}
+	jobHandler.markScheduled()
+}
+
+// scheduleTransfer puts the transfer msg into the coordinator transfer channel of given priority
+func scheduleTransfer(transferMsg TransferMsg, priority uint8, coordiatorChannels *CoordinatorChannels, logger *common.Logger){
+	switch priority{
+	case HighJobPriority:
+		coordiatorChannels.HighTransfer <- transferMsg
+		logger.Debug("successfully scheduled transfer %v with priority %v for Job %v and part number %v", transferMsg.TransferIndex, priority, string(transferMsg.Id), transferMsg.PartNumber)
+	case MediumJobPriority:
+		coordiatorChannels.MedTransfer <- transferMsg
+	case LowJobPriority:
+		coordiatorChannels.LowTransfer <- transferMsg
+	default:
+		logger.Debug("invalid job part order priority %d for given Job Id %s and part number %d and transfer Index %d", priority, transferMsg.Id, transferMsg.PartNumber, transferMsg.TransferIndex)
+	}
+}
+
+// resumeJob api reschedules the transfers of an existing job which have not completed yet
+/*
+	* transfers which are already complete are not scheduled again
+	* transfers still in flight in the current instance of transfer engine are not scheduled again
+	* every other transfer gets a new context, is marked active and is scheduled with the priority of its job part
+	* prologue of the transfer skips the chunks which were completed before, unless the source changed since it got planned
+	* the credentials of S3 and GCS sources given by the resume order replace the ones the parts hold, if any,
+	  since parts rebuilt from their job part plan files hold none
+ */
+func resumeJob(jobId common.JobID, resumeOrder common.ResumeJobOrder, coordiatorChannels *CoordinatorChannels, jPartPlanInfoMap *JobPartPlanInfoMap, resp *http.ResponseWriter){
+	jPartMap, ok := jPartPlanInfoMap.LoadPartPlanMapforJob(jobId)
+	if !ok{
+		(*resp).WriteHeader(http.StatusBadRequest)
+		(*resp).Write([]byte(fmt.Sprintf("no existing job with JobId %s", jobId)))
+		return
+	}
+
+	var numTransfersResumed uint32 = 0
+	for partNo, jHandler := range jPartMap{
+		jPartPlan := jHandler.getJobPartPlanPointer()
+		if jPartPlan.SrcLocationType == common.S3 && resumeOrder.S3Credentials.AccessKeyId != ""{
+			jHandler.setSourceCredentials(resumeOrder.S3Credentials)
+		} else if jPartPlan.SrcLocationType == common.GCS && resumeOrder.GCSCredentials.AccessKeyId != ""{
+			jHandler.setSourceCredentials(resumeOrder.GCSCredentials)
+		}
+		// the contexts are replaced under the lock of the part, while the transfers are scheduled without it
+		// since the workers read the contexts of the transfers they process
+		for _, index := range jHandler.prepareResume(){
+			transferCtx, _ := jHandler.transferContext(index)
+			scheduleTransfer(TransferMsg{jobId, partNo, index, jPartPlanInfoMap, transferCtx}, jPartPlan.Priority, coordiatorChannels, jHandler.Logger)
+			numTransfersResumed += 1
+		}
+		jHandler.Logger.Info("resumed part number %d of job %s", partNo, jobId)
+	}
+	(*resp).WriteHeader(http.StatusAccepted)
+	(*resp).Write([]byte(fmt.Sprintf("resumed %d transfers of job %s", numTransfersResumed, jobId)))
+}
+
+// cancelJob api cancels every part of an existing job
+/*
+	* cancelling the context of a part stops the scheduling of its chunks and aborts the requests in flight
+	* transfers which are not complete or failed yet are marked cancelled in the job part plan file
+	* chunks parked while the job is paused are scheduled again, so that they drain
+	* a cancelled job can be resumed later on
+ */
+func cancelJob(jobId common.JobID, coordiatorChannels *CoordinatorChannels, jPartPlanInfoMap *JobPartPlanInfoMap, resp *http.ResponseWriter){
+	jPartMap, ok := jPartPlanInfoMap.LoadPartPlanMapforJob(jobId)
+	if !ok{
+		(*resp).WriteHeader(http.StatusBadRequest)
+		(*resp).Write([]byte(fmt.Sprintf("no existing job with JobId %s", jobId)))
+		return
+	}
+
+	var numTransfersCancelled uint32 = 0
+	for partNo, jHandler := range jPartMap{
+		numTransfersCancelled += cancelJobPart(jHandler)
+		parkedChunkMsgs := jHandler.takeParkedChunkMsgs()
+		// the parked chunks are scheduled in the background since the channel may be full
+		go func(){
+			for _, chunkMsg := range parkedChunkMsgs{
+				coordiatorChannels.HighChunkTransaction <- chunkMsg
+			}
+		}()
+		jHandler.Logger.Info("cancelled part number %d of job %s", partNo, jobId)
+	}
+	(*resp).WriteHeader(http.StatusAccepted)
+	(*resp).Write([]byte(fmt.Sprintf("cancelled %d transfers of job %s", numTransfersCancelled, jobId)))
+}
+
+// cancelJobPart cancels the context of given job part and marks its active transfers cancelled
+// returns the number of transfers cancelled
+func cancelJobPart(jHandler *JobPartPlanInfo) (uint32){
+	jPartPlan := jHandler.getJobPartPlanPointer()
+	jPartPlan.IsCancelled = true
+	_, cancel := jHandler.partContext()
+	cancel()
+	var numTransfersCancelled uint32 = 0
+	for index := uint32(0); index < jPartPlan.NumTransfers; index++{
+		transferHeader := jHandler.Transfer(index)
+		if transferHeader.Status != common.TransferStatusActive{
+			continue
+		}
+		transferHeader.Status = common.TransferStatusCancelled
+		transferHeader.CompletionTime = uint64(time.Now().UnixNano())
+		numTransfersCancelled += 1
+	}
+	return numTransfersCancelled
+}
+
+// cleanJobs api removes the completed jobs from the transfer engine, along with their job part plan files and log files
+/*
+	* if a JobId is given, only that job is removed, provided it completed
+	* otherwise every job that completed more than olderThan ago is removed
+	* without olderThan, the jobs are only removed once their time to live has passed
+ */
+func cleanJobs(jobId common.JobID, olderThan *time.Duration, jPartPlanInfoMap *JobPartPlanInfoMap, jobToLoggerMap *JobToLoggerMap, resp *http.ResponseWriter){
+	if jobId == ""{
+		cleanedJobIds := cleanExpiredJobs(olderThan, jPartPlanInfoMap, jobToLoggerMap)
+		(*resp).WriteHeader(http.StatusAccepted)
+		(*resp).Write([]byte(fmt.Sprintf("cleaned %d jobs %v", len(cleanedJobIds), cleanedJobIds)))
+		return
+	}
+
+	jPartMap, ok := jPartPlanInfoMap.LoadPartPlanMapforJob(jobId)
+	if !ok{
+		(*resp).WriteHeader(http.StatusBadRequest)
+		(*resp).Write([]byte(fmt.Sprintf("no existing job with JobId %s", jobId)))
+		return
+	}
+	completionTime, isComplete := getJobCompletionTime(jPartMap)
+	expiresAfter := getJobTTLAfterCompletion(jPartMap)
+	if olderThan != nil{
+		expiresAfter = *olderThan
+	}
+	if !isComplete || time.Since(completionTime) < expiresAfter{
+		(*resp).WriteHeader(http.StatusBadRequest)
+		(*resp).Write([]byte(fmt.Sprintf("job %s has not completed more than %v ago", jobId, expiresAfter)))
+		return
+	}
+	if !cleanJob(jobId, jPartPlanInfoMap, jobToLoggerMap){
+		(*resp).WriteHeader(http.StatusBadRequest)
+		(*resp).Write([]byte(fmt.Sprintf("job %s still has chunks in flight, try again once they are drained", jobId)))
+		return
+	}
+	(*resp).WriteHeader(http.StatusAccepted)
+	(*resp).Write([]byte(fmt.Sprintf("cleaned job %s", jobId)))
+}
+
+// isJobCancelled returns true if any existing part of the job with given JobId has been cancelled
+// the cancelled state is read from the job part plan, so that it survives a restart of the transfer engine
+func isJobCancelled(jobId common.JobID, jPartPlanInfoMap *JobPartPlanInfoMap) (bool){
+	jPartMap, ok := jPartPlanInfoMap.LoadPartPlanMapforJob(jobId)
+	if !ok{
+		return false
+	}
+	for _, jHandler := range jPartMap{
+		if jHandler.getJobPartPlanPointer().IsCancelled{
+			return true
 		}
 	}
+	return false
+}
+
+// pauseJob api pauses every part of an existing job
+/*
+	* workers park the transfer and chunk msgs of a paused job instead of processing them
+	* chunks in flight complete, and the job part plan files stay memory mapped
+ */
+func pauseJob(jobId common.JobID, jPartPlanInfoMap *JobPartPlanInfoMap, resp *http.ResponseWriter){
+	jPartMap, ok := jPartPlanInfoMap.LoadPartPlanMapforJob(jobId)
+	if !ok{
+		(*resp).WriteHeader(http.StatusBadRequest)
+		(*resp).Write([]byte(fmt.Sprintf("no existing job with JobId %s", jobId)))
+		return
+	}
+	for partNo, jHandler := range jPartMap{
+		jHandler.pause()
+		jHandler.Logger.Info("paused part number %d of job %s", partNo, jobId)
+	}
+	(*resp).WriteHeader(http.StatusAccepted)
+	(*resp).Write([]byte(fmt.Sprintf("paused job %s", jobId)))
+}
+
+// unpauseJob api unpauses every part of an existing job and schedules the msgs parked while the job was paused
+func unpauseJob(jobId common.JobID, coordiatorChannels *CoordinatorChannels, jPartPlanInfoMap *JobPartPlanInfoMap, resp *http.ResponseWriter){
+	jPartMap, ok := jPartPlanInfoMap.LoadPartPlanMapforJob(jobId)
+	if !ok{
+		(*resp).WriteHeader(http.StatusBadRequest)
+		(*resp).Write([]byte(fmt.Sprintf("no existing job with JobId %s", jobId)))
+		return
+	}
+	for partNo, jHandler := range jPartMap{
+		parkedTransferMsgs, parkedChunkMsgs := jHandler.unpause()
+		priority := jHandler.getJobPartPlanPointer().Priority
+		logger := jHandler.Logger
+		// the parked msgs are scheduled in the background since the channels may be full
+		// chunks are scheduled before the transfers, so that the transfers in flight finish first
+		go func(){
+			for _, chunkMsg := range parkedChunkMsgs{
+				coordiatorChannels.HighChunkTransaction <- chunkMsg
+			}
+			for _, transferMsg := range parkedTransferMsgs{
+				scheduleTransfer(transferMsg, priority, coordiatorChannels, logger)
+			}
+		}()
+		jHandler.Logger.Info("unpaused part number %d of job %s with %d transfers and %d chunks parked", partNo, jobId, len(parkedTransferMsgs), len(parkedChunkMsgs))
+	}
+	(*resp).WriteHeader(http.StatusAccepted)
+	(*resp).Write([]byte(fmt.Sprintf("unpaused job %s", jobId)))
+}
+
+// isJobPaused returns true if any existing part of the job with given JobId is paused
+func isJobPaused(jobId common.JobID, jPartPlanInfoMap *JobPartPlanInfoMap) (bool){
+	jPartMap, ok := jPartPlanInfoMap.LoadPartPlanMapforJob(jobId)
+	if !ok{
+		return false
+	}
+	for _, jHandler := range jPartMap{
+		jHandler.pauseLock.Lock()
+		isPaused := jHandler.isPaused
+		jHandler.pauseLock.Unlock()
+		if isPaused{
+			return true
+		}
+	}
+	return false
+}
+
+// targetNumOfWorkers is the number of workers the execution engine was last asked to scale to
+var targetNumOfWorkers int32 = common.DefaultNumOfEngineWorkers
+
+// scaleWorkers api asks the execution engine to scale to the given number of workers
+func scaleWorkers(count string, coordiatorChannels *CoordinatorChannels, resp *http.ResponseWriter){
+	numOfWorkers, err := strconv.Atoi(count)
+	if err != nil || numOfWorkers <= 0{
+		(*resp).WriteHeader(http.StatusBadRequest)
+		(*resp).Write([]byte(fmt.Sprintf("invalid number of workers %s", count)))
+		return
+	}
+	atomic.StoreInt32(&targetNumOfWorkers, int32(numOfWorkers))
+	coordiatorChannels.WorkerCount <- numOfWorkers
+	(*resp).WriteHeader(http.StatusAccepted)
+	(*resp).Write([]byte(fmt.Sprintf("scaling the execution engine to %d workers", numOfWorkers)))
+}
+
+// getNumOfWorkers api returns the number of workers the execution engine was last asked to scale to
+func getNumOfWorkers(resp *http.ResponseWriter){
+	(*resp).WriteHeader(http.StatusAccepted)
+	(*resp).Write([]byte(strconv.Itoa(int(atomic.LoadInt32(&targetNumOfWorkers)))))
 }
 
 // getJobSummary api returns the job progress summary of an active job
@@ -144,12 +399,15 @@ func ExecuteNewCopyJobPartOrder(payload common.CopyJobPartOrder, coordiatorChann
 	* CompleteJobOrdered - determines whether final part of job has been ordered or not
 	* TotalNumberOfTransfer - total number of transfers available for the given job
 	* TotalNumberofTransferCompleted - total number of transfers in the job completed
+	* TotalNumberofTransferCancelled - total number of transfers in the job cancelled
 	* NumberOfTransferCompletedafterCheckpoint - number of transfers completed after the last checkpoint
 	* NumberOfTransferFailedAfterCheckpoint - number of transfers failed after last checkpoint timestamp
 	* PercentageProgress - job progress reported in terms of percentage
+	* NumberOfChunksTransferred and ChunkLatency percentiles - latencies of the chunks transferred by the current instance of transfer engine,
+	  only if withChunkLatencies is set, as by the bench command
 	* FailedTransfers - list of transfer after last checkpoint timestamp that failed.
  */
-func getJobSummary(jobId common.JobID, jPartPlanInfoMap *JobPartPlanInfoMap, resp *http.ResponseWriter){
+func getJobSummary(jobId common.JobID, withChunkLatencies bool, jPartPlanInfoMap *JobPartPlanInfoMap, resp *http.ResponseWriter){
 
 	//fmt.Println("received a get job order status request for JobId ", jobId)
 	// getJobPartMapFromJobPartInfoMap gives the map of partNo to JobPartPlanInfo Pointer for a given JobId
@@ -168,15 +426,24 @@ func getJobSummary(jobId common.JobID, jPartPlanInfoMap *JobPartPlanInfoMap, res
 	// failedTransfers represents the list of transfers which failed after the last checkpoint timestamp
 	var failedTransfers []common.TransferStatus
 
+	// chunkLatencies counts the latencies of the chunks transferred by all the parts of the job, only when they are asked for
+	var chunkLatencies latencyHistogram
+
 	progressSummary := common.JobProgressSummary{}
 	for _, jHandler := range jPartMap{
 		//fmt.Println("part no ", partNo)
+		if withChunkLatencies{
+			jHandler.chunkLatencyLock.Lock()
+			chunkLatencies.merge(&jHandler.chunkLatencies)
+			jHandler.chunkLatencyLock.Unlock()
+		}
 
 		// currentJobPartPlanInfo represents the memory map JobPartPlanHeader for current partNo
 		currentJobPartPlanInfo := jHandler.getJobPartPlanPointer()
 
 		completeJobOrdered = completeJobOrdered || currentJobPartPlanInfo.IsFinalPart
 		progressSummary.TotalNumberOfTransfer += currentJobPartPlanInfo.NumTransfers
+		progressSummary.TotalNumberOfFilteredEntities += currentJobPartPlanInfo.NumFilteredEntities
 		// iterating through all transfers for current partNo and job with given jobId
 		for index := uint32(0); index < currentJobPartPlanInfo.NumTransfers; index++{
 
@@ -193,11 +460,19 @@ func getJobSummary(jobId common.JobID, jPartPlanInfoMap *JobPartPlanInfoMap, res
 				// appending to list of failed transfer
 				failedTransfers = append(failedTransfers, common.TransferStatus{source, destination, common.TransferStatusFailed})
 			}
+			if transferHeader.Status == common.TransferStatusCancelled{
+				progressSummary.TotalNumberofTransferCancelled += 1
+			}
 		}
 	}
+	// numberOfTransfersDone represents the number of transfers which are not active anymore
+	numberOfTransfersDone := progressSummary.TotalNumberofTransferCompleted + progressSummary.TotalNumberofFailedTransfer + progressSummary.TotalNumberofTransferCancelled
 	 /*If each transfer in all parts of a job has either completed or failed and is not in active or inactive state, then job order is said to be completed
 	 if final part of job has been ordered.*/
-	if (progressSummary.TotalNumberOfTransfer == progressSummary.TotalNumberofFailedTransfer + progressSummary.TotalNumberofTransferCompleted) &&(
+	if progressSummary.TotalNumberofTransferCancelled > 0 && progressSummary.TotalNumberOfTransfer == numberOfTransfersDone{
+		// a cancelled job does not wait for the final part to be ordered
+		progressSummary.JobStatus = common.StatusCancelled
+	}else if (progressSummary.TotalNumberOfTransfer == numberOfTransfersDone) &&(
 		completeJobOrdered){
 			progressSummary.JobStatus = common.StatusCompleted
 	}else{
@@ -205,7 +480,18 @@ func getJobSummary(jobId common.JobID, jPartPlanInfoMap *JobPartPlanInfoMap, res
 	}
 	progressSummary.CompleteJobOrdered = completeJobOrdered
 	progressSummary.FailedTransfers = failedTransfers
-	progressSummary.PercentageProgress = (( progressSummary.TotalNumberofTransferCompleted  + progressSummary.TotalNumberofFailedTransfer) *100)/ progressSummary.TotalNumberOfTransfer
+	// a job with no transfers at all (i.e. nothing matched the enumeration) is as far as it will ever get
+	if progressSummary.TotalNumberOfTransfer == 0 {
+		progressSummary.PercentageProgress = 100
+	} else {
+		progressSummary.PercentageProgress = (numberOfTransfersDone * 100) / progressSummary.TotalNumberOfTransfer
+	}
+
+	// get the percentiles of the chunk latencies
+	progressSummary.NumberOfChunksTransferred = chunkLatencies.total
+	progressSummary.ChunkLatencyP50InMilliseconds = chunkLatencies.percentileInMilliseconds(50)
+	progressSummary.ChunkLatencyP90InMilliseconds = chunkLatencies.percentileInMilliseconds(90)
+	progressSummary.ChunkLatencyP99InMilliseconds = chunkLatencies.percentileInMilliseconds(99)
 
 	// get the throughput counts
 	numOfBytesTransferredSinceLastCheckpoint := atomic.LoadInt64(&realTimeThroughputCounter.currentBytes) - realTimeThroughputCounter.lastCheckedBytes
@@ -324,8 +610,9 @@ func serveRequest(resp http.ResponseWriter, req *http.Request, coordinatorChanne
 	switch req.Method {
 	case "GET":
 		// request type defines the type of GET request supported by transfer engine
-		// currently Transfer Engine is supporting list and kill type of GET request
+		// currently Transfer Engine is supporting list, workers and kill type of GET request
 		// list type is used by the request for list commands
+		// workers type is used to get the number of workers the execution engine was last asked to scale to
 		// kill type is used when frontend wants the existing instance of transfer engine to kill itself
 		var requestType = req.URL.Query()["Type"][0]
 		switch requestType {
@@ -340,10 +627,12 @@ func serveRequest(resp http.ResponseWriter, req *http.Request, coordinatorChanne
 			if lsCommand.JobId == "" {
 				listExistingJobs(jPartPlanInfoMap, &resp)
 			} else if lsCommand.ExpectedTransferStatus == math.MaxUint8 {
-				getJobSummary(lsCommand.JobId, jPartPlanInfoMap, &resp)
+				getJobSummary(lsCommand.JobId, lsCommand.WithChunkLatencies, jPartPlanInfoMap, &resp)
 			} else {
 				getTransferList(lsCommand.JobId, lsCommand.ExpectedTransferStatus, jPartPlanInfoMap, &resp)
 			}
+		case "workers":
+			getNumOfWorkers(&resp)
 		case "kill":
 			fmt.Println("killing the transfer engine as per the request")
 			os.Exit(1)
@@ -359,8 +648,63 @@ func serveRequest(resp http.ResponseWriter, req *http.Request, coordinatorChanne
 		resp.WriteHeader(http.StatusBadRequest)
 		resp.Write([]byte("Not able to trigger the AZCopy request"))
 	case "PUT":
-
+		// request type defines the type of PUT request supported by transfer engine
+		// resume type is used to reschedule the incomplete transfers of an existing job
+		// pause and unpause types are used to stop and restart handing the transfers of an existing job to the workers
+		// workers type is used to scale the number of workers of the execution engine, it does not apply to a single job
+		var requestType = req.URL.Query()["Type"][0]
+		var jobId = common.JobID(req.URL.Query()["JobId"][0])
+		switch requestType {
+		case "resume":
+			// the body of a resume request holds the credentials of the sources of the job, if any
+			var resumeOrder common.ResumeJobOrder
+			body, err := ioutil.ReadAll(req.Body)
+			if err == nil && len(body) > 0{
+				err = json.Unmarshal(body, &resumeOrder)
+			}
+			if err != nil{
+				resp.WriteHeader(http.StatusBadRequest)
+				resp.Write([]byte(fmt.Sprintf("invalid resume order: %s", err.Error())))
+				return
+			}
+			resumeJob(jobId, resumeOrder, coordinatorChannels, jPartPlanInfoMap, &resp)
+		case "pause":
+			pauseJob(jobId, jPartPlanInfoMap, &resp)
+		case "unpause":
+			unpauseJob(jobId, coordinatorChannels, jPartPlanInfoMap, &resp)
+		case "workers":
+			scaleWorkers(req.URL.Query().Get("Count"), coordinatorChannels, &resp)
+		default:
+			resp.WriteHeader(http.StatusBadRequest)
+			resp.Write([]byte(fmt.Sprintf("request type %s is not supported by STE", requestType)))
+		}
 	case "DELETE":
+		// request type defines the type of DELETE request supported by transfer engine
+		// cancel type is used to cancel all the transfers of an existing job
+		// clean type is used to remove completed jobs, the job part plan files and log files included
+		var requestType = req.URL.Query()["Type"][0]
+		var jobId = common.JobID(req.URL.Query()["JobId"][0])
+		switch requestType {
+		case "cancel":
+			cancelJob(jobId, coordinatorChannels, jPartPlanInfoMap, &resp)
+		case "clean":
+			// OlderThan is given in seconds, the time to live of the jobs applies when it is not given
+			var olderThan *time.Duration
+			if olderThanParam := req.URL.Query().Get("OlderThan"); olderThanParam != ""{
+				olderThanSeconds, err := strconv.ParseInt(olderThanParam, 10, 64)
+				if err != nil{
+					resp.WriteHeader(http.StatusBadRequest)
+					resp.Write([]byte(fmt.Sprintf("invalid OlderThan parameter %s", olderThanParam)))
+					return
+				}
+				olderThanDuration := time.Duration(olderThanSeconds) * time.Second
+				olderThan = &olderThanDuration
+			}
+			cleanJobs(jobId, olderThan, jPartPlanInfoMap, jobToLoggerMap, &resp)
+		default:
+			resp.WriteHeader(http.StatusBadRequest)
+			resp.Write([]byte(fmt.Sprintf("request type %s is not supported by STE", requestType)))
+		}
 
 	default:
 		fmt.Println("Operation Not Supported by STE")
@@ -388,11 +732,15 @@ func InitializedChannels() (*CoordinatorChannels, *EEChannels){
 
 	// Create suicide channel which is used to scale back on the number of workers
 	SuicideChannel := make(chan SuicideJob, 100)
+	// WorkerCountChannel takes the number of workers the execution engine should scale to
+	WorkerCountChannel := make(chan int, 10)
 
 	transferEngineChannel := &CoordinatorChannels{
 		HighTransfer : HighTransferMsgChannel,
 		MedTransfer	: MedTransferMsgChannel,
 		LowTransfer : LowTransferMsgChannel,
+		HighChunkTransaction : HighChunkMsgChannel,
+		WorkerCount : WorkerCountChannel,
 	}
 
 	executionEngineChanel := &EEChannels{
@@ -403,6 +751,7 @@ func InitializedChannels() (*CoordinatorChannels, *EEChannels){
 		MedChunkTransaction:MedChunkMsgChannel,
 		LowChunkTransaction:LowChunkMsgChannel,
 		SuicideChannel: SuicideChannel,
+		WorkerCount: WorkerCountChannel,
 	}
 	return transferEngineChannel, executionEngineChanel
 }
@@ -416,7 +765,11 @@ func initializeCoordinator(coordinatorChannels *CoordinatorChannels) (error){
 
 	jobHandlerMap := NewJobPartPlanInfoMap()
 	jobLoggerMap := NewJobToLoggerMap()
-	reconstructTheExistingJobPart(jobHandlerMap)
+	reconstructTheExistingJobPart(jobHandlerMap, jobLoggerMap)
+	// completed jobs are swept in the background only if a sweep interval is configured
+	if sweepInterval, err := time.ParseDuration(os.Getenv(jobSweepIntervalEnvVar)); err == nil && sweepInterval > 0{
+		go sweepExpiredJobs(sweepInterval, jobHandlerMap, jobLoggerMap)
+	}
 	http.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
 		serveRequest(writer, request, coordinatorChannels, jobHandlerMap, jobLoggerMap)
 	})
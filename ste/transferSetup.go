@@ -0,0 +1,125 @@
+package ste
+
+import (
+	"context"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/2016-05-31/azblob"
+	"github.com/edsrzf/mmap-go"
+	"net/url"
+	"os"
+	"time"
+)
+
+// newTransferBlobURL creates the url of a source or destination blob, with the pipeline the chunks of transfers are sent with
+func newTransferBlobURL(rawUrl string) azblob.BlobURL {
+	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{
+		Retry: azblob.RetryOptions{
+			Policy:        azblob.RetryPolicyExponential,
+			MaxTries:      3,
+			TryTimeout:    time.Second * 60,
+			RetryDelay:    time.Second * 1,
+			MaxRetryDelay: time.Second * 3,
+		},
+	})
+	u, _ := url.Parse(rawUrl)
+	return azblob.NewBlobURL(*u, p)
+}
+
+// getCompletedChunks returns which chunks of the transfer were completed before the job got resumed, along with their number
+func getCompletedChunks(transfer TransferMsgDetail, numOfChunks uint32) ([]bool, uint32) {
+	chunkIsCompleted := make([]bool, numOfChunks)
+	numOfChunksCompleted := uint32(0)
+	for chunkIndex := uint32(0); chunkIndex < numOfChunks; chunkIndex++ {
+		chunkStatus, _ := getChunkStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, uint16(chunkIndex), transfer.JobHandlerMap)
+		if chunkStatus == ChunkTransferStatusComplete {
+			chunkIsCompleted[chunkIndex] = true
+			numOfChunksCompleted++
+		}
+	}
+	return chunkIsCompleted, numOfChunksCompleted
+}
+
+// mapSourceFile maps in the local source of the transfer, the transfer fails if the source cannot be mapped in
+// the returned chunkRefCount holds the reference of the prologue, the source stays mapped until every chunk scheduled
+// with it is done, however the chunks end
+func mapSourceFile(transfer TransferMsgDetail) (mmap.MMap, *chunkRefCount, bool) {
+	memoryMappedFile, err := openAndMemoryMapFile(transfer.Source)
+	if err != nil {
+		logger := getLoggerFromJobPartPlanInfo(transfer.JobId, transfer.PartNumber, transfer.JobHandlerMap)
+		logger.Error("failed to open the source of Transfer job with jobId %s and partNum %d and transferId %d due to error %s", transfer.JobId, transfer.PartNumber, transfer.TransferId, err.Error())
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+		return nil, nil, false
+	}
+	return memoryMappedFile, newUnmapRefCount(transfer, memoryMappedFile), true
+}
+
+// mapDestinationFile creates the local destination of the transfer with the given size and maps it in, as mapSourceFile does
+// the file of a resumed transfer already holds the chunks completed before, so it is only opened if it has the given size,
+// otherwise it is created again and none of the chunks counts as completed anymore
+func mapDestinationFile(transfer TransferMsgDetail, fileSize int64, chunkIsCompleted []bool, numOfChunksCompleted *uint32) (mmap.MMap, *chunkRefCount, bool) {
+	var memoryMappedFile mmap.MMap
+	var err error
+	if fileInfo, statErr := os.Stat(transfer.Destination); *numOfChunksCompleted > 0 && statErr == nil && fileInfo.Size() == fileSize {
+		memoryMappedFile, err = openAndMemoryMapFile(transfer.Destination)
+	} else {
+		for chunkIndex := range chunkIsCompleted {
+			chunkIsCompleted[chunkIndex] = false
+		}
+		*numOfChunksCompleted = 0
+		memoryMappedFile, err = createAndMemoryMapFile(transfer.Destination, fileSize)
+	}
+	if err != nil {
+		logger := getLoggerFromJobPartPlanInfo(transfer.JobId, transfer.PartNumber, transfer.JobHandlerMap)
+		logger.Error("failed to create the destination of Transfer job with jobId %s and partNum %d and transferId %d due to error %s", transfer.JobId, transfer.PartNumber, transfer.TransferId, err.Error())
+		updateTransferStatus(transfer.JobId, transfer.PartNumber, transfer.TransferId, common.TransferStatusFailed, transfer.JobHandlerMap)
+		return nil, nil, false
+	}
+	return memoryMappedFile, newUnmapRefCount(transfer, memoryMappedFile), true
+}
+
+// newUnmapRefCount returns a chunkRefCount which unmaps the memory mapped file of the transfer once released
+func newUnmapRefCount(transfer TransferMsgDetail, memoryMappedFile mmap.MMap) *chunkRefCount {
+	return newChunkRefCount(func() {
+		if err := memoryMappedFile.Unmap(); err != nil {
+			logger := getLoggerFromJobPartPlanInfo(transfer.JobId, transfer.PartNumber, transfer.JobHandlerMap)
+			logger.Error("failed to unmap the file of Transfer job with jobId %s and partNum %d and transferId %d", transfer.JobId, transfer.PartNumber, transfer.TransferId)
+		}
+	})
+}
+
+// scheduleChunks schedules a chunkMsg for each chunk of the source which was not completed before the job got resumed
+// generateChunkFunc generates the function transferring the chunk, which must give back the reference it holds on refCount
+// the reference of the prologue is given back once every chunkMsg is scheduled
+func scheduleChunks(transfer TransferMsgDetail, chunkChannel chan<- ChunkMsg, sourceSize int64, chunkIsCompleted []bool, refCount *chunkRefCount,
+	generateChunkFunc func(chunkId int32, startIndex int64, chunkSize int64) chunkFunc) {
+	chunkSize := int64(transfer.ChunkSize)
+	chunkId := int32(0)
+	for startIndex := int64(0); startIndex < sourceSize; startIndex += chunkSize {
+		adjustedChunkSize := chunkSize
+
+		// compute actual size of the chunk
+		if startIndex+chunkSize > sourceSize {
+			adjustedChunkSize = sourceSize - startIndex
+		}
+
+		if !chunkIsCompleted[chunkId] {
+			refCount.schedule(ChunkMsg{
+				jobId:            transfer.JobId,
+				partNumber:       transfer.PartNumber,
+				jPartPlanInfoMap: transfer.JobHandlerMap,
+				doTransfer:       generateChunkFunc(chunkId, startIndex, adjustedChunkSize),
+			}, chunkChannel)
+		}
+		chunkId += 1
+	}
+	refCount.done()
+}
+
+// isChunkCancelled returns whether the transfer of the chunk got cancelled or failed, the chunks of such a transfer are skipped
+func isChunkCancelled(ctx context.Context, workerId int, chunkId int32, transferIdentifierStr string, logger *common.Logger) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+	logger.Debug("worker %d is skipping Chunk job with %s and chunkId %d since the transfer was cancelled", workerId, transferIdentifierStr, chunkId)
+	return true
+}